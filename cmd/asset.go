@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Largest asset followAsset downloads, so that a link to a huge archive can't keep a run busy for long.
+const maxAssetSize = 256 << 20
+
+// Asset is a file a page links to, ex. a Postman collection, as hashed by followAsset.
+type Asset struct {
+	Size   int64
+	SHA256 string
+}
+
+type cachedAsset struct {
+	once  sync.Once
+	asset *Asset
+	err   error
+}
+
+// Asset downloads the file and hashes its bytes, at most once per run, same as Get.
+func (c *DocCache) Asset(ctx context.Context, url string, opts FetchOptions) (*Asset, error) {
+	cacheKey := docKey{url: url, opts: opts}
+	c.mu.Lock()
+	cached, ok := c.assets[cacheKey]
+	if !ok {
+		cached = &cachedAsset{}
+		c.assets[cacheKey] = cached
+	}
+	c.mu.Unlock()
+
+	cached.once.Do(func() {
+		release, err := c.Hosts.Acquire(ctx, url)
+		if err != nil {
+			cached.err = err
+			return
+		}
+		defer release()
+		cached.asset, cached.err = fetchAsset(ctx, url, opts)
+	})
+	return cached.asset, cached.err
+}
+
+// fetchAsset streams the file into its hash, without keeping it. A body shorter than the Content-Length it was announced with is an error,
+// rather than the checksum of a truncated download, which would look like a change.
+func fetchAsset(ctx context.Context, url string, opts FetchOptions) (*Asset, error) {
+	resp, err := get(ctx, url, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, classifyTLS(err))
+	}
+	defer resp.Body.Close()
+	if !opts.AcceptStatus.Accepts(resp.StatusCode) {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if size > maxAssetSize {
+		return nil, fmt.Errorf("%s is larger than %d MiB, too large to hash", url, maxAssetSize>>20)
+	}
+	// -1 when unknown, ex. for compressed responses.
+	if resp.ContentLength >= 0 && size != resp.ContentLength {
+		return nil, fmt.Errorf("downloaded %d bytes of %s, which announced %d", size, url, resp.ContentLength)
+	}
+	return &Asset{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// followAsset downloads the file linked by the first element with an href (or src) that htmlClass matches on the page, and returns its checksum
+// as the content, so that the target changes with the file rather than with the page. Relative links are resolved against the page's url.
+func followAsset(ctx context.Context, page *Page, htmlClass string, entry *Entry, args RunArgs) (string, error) {
+	nodes, err := selectMatches(page.Doc, htmlClass, args.extraction(entry))
+	if err != nil {
+		return "", err
+	}
+	var href string
+	for _, node := range nodes {
+		link := goquery.NewDocumentFromNode(node).Selection
+		if href = strings.TrimSpace(link.AttrOr("href", link.AttrOr("src", ""))); href != "" {
+			break
+		}
+	}
+	if href == "" {
+		return "", fmt.Errorf("%s matched no link with an href on %s", htmlClass, page.URL)
+	}
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return "", err
+	}
+	assetURL, err := base.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("invalid asset href %q on %s: %w", href, page.URL, err)
+	}
+	assetURL.Fragment = ""
+	opts := args.fetchOptions(assetURL.String(), entry)
+	// The target's accept and range are for the page.
+	opts.Accept, opts.JSON, opts.YAML, opts.Range = "*/*", false, false, ""
+	asset, err := args.Docs.Asset(ctx, assetURL.String(), opts)
+	if err != nil {
+		return "", fmt.Errorf("asset: %w", err)
+	}
+	return fmt.Sprintf("sha256:%s (%d bytes)", asset.SHA256, asset.Size), nil
+}
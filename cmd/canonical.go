@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// canonicalURL is the url the page declares as its canonical one with <link rel="canonical">, if that's not pageURL itself.
+// Fragments are ignored in the comparison, as they never reach the server.
+func canonicalURL(doc *goquery.Document, baseURL, pageURL string) string {
+	href, ok := doc.Find(`link[rel="canonical"]`).First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" {
+		return ""
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	canonical, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	canonical.Fragment = ""
+	tracked, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	tracked.Fragment = ""
+	if canonical.String() == tracked.String() {
+		return ""
+	}
+	return canonical.String()
+}
+
+// reportCanonical lists the targets tracked under an alias of their page, and with useCanonical, moves them over to the canonical url, along with their snapshots.
+// The fragment of the tracked url, like "#change-log", is kept. Targets whose canonical url is already tracked are left as they are, as duplicates to clean up.
+func reportCanonical(hashes Hashes, useCanonical bool, args RunArgs) error {
+	var aliases []string
+	for key, entry := range hashes {
+		if entry.Canonical != "" {
+			aliases = append(aliases, key)
+		}
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+	sort.Strings(aliases)
+
+	if !useCanonical {
+		fmt.Fprintf(os.Stderr, "%d targets are tracked under another url than the canonical one their page declares; --use-canonical moves them over:\n", len(aliases))
+	}
+	for _, key := range aliases {
+		entry := hashes[key]
+		oldURL, htmlClass, _ := splitKey(key)
+		newURL := entry.Canonical
+		if parsed, err := url.Parse(oldURL); err == nil && parsed.Fragment != "" {
+			newURL += "#" + parsed.Fragment
+		}
+		newKey := joinKey(newURL, htmlClass)
+		if !useCanonical {
+			fmt.Fprintf(os.Stderr, "  %s -> %s (%s)\n", oldURL, newURL, htmlClass)
+			continue
+		}
+		if _, ok := hashes[newKey]; ok {
+			fmt.Fprintf(os.Stderr, "%s (%s) is an alias of %s, which is already tracked; one of them can be removed\n", oldURL, htmlClass, newURL)
+			continue
+		}
+		if args.DryRun {
+			fmt.Printf("Would move %s to its canonical url %s (%s)\n", oldURL, newURL, htmlClass)
+			continue
+		}
+		entry.Canonical = ""
+		hashes[newKey] = entry
+		delete(hashes, key)
+		if args.Snapshots != nil {
+			if err := args.Snapshots.Rename(key, newKey); err != nil {
+				return fmt.Errorf("failed to move the snapshots of %q: %w", key, err)
+			}
+		}
+		fmt.Printf("Moved %s to its canonical url %s (%s)\n", oldURL, newURL, htmlClass)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangelogEntry is a change that goes into the --changelog-file.
+type ChangelogEntry struct {
+	Key  string
+	ID   string
+	Diff []DiffLine
+	// Whether there was a snapshot to diff against. Without one, there's no diff to show.
+	HasPrevious bool
+}
+
+// Changelog collects the changes of a run, to be written as a markdown section at the end of it.
+// A nil *Changelog discards everything.
+type Changelog struct {
+	mu      sync.Mutex
+	entries []ChangelogEntry
+}
+
+func (l *Changelog) Add(entry ChangelogEntry) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// Prepend writes a section for the run at the top of the markdown file at filePath, newest first, leaving the rest of it as it was.
+// Nothing is written if nothing changed.
+func (l *Changelog) Prepend(filePath string, at time.Time) error {
+	if l == nil || len(l.entries) == 0 {
+		return nil
+	}
+	existing, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	sort.Slice(l.entries, func(i, j int) bool { return l.entries[i].Key < l.entries[j].Key })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", at.Local().Format(time.DateTime))
+	for _, entry := range l.entries {
+		url, htmlClass, _ := splitKey(entry.Key)
+		fmt.Fprintf(&b, "### %s\n\n[%s](%s) `%s`\n\n", entry.ID, url, url, htmlClass)
+		if !entry.HasPrevious {
+			b.WriteString("No earlier snapshot to diff against.\n\n")
+			continue
+		}
+		changed := 0
+		for _, line := range entry.Diff {
+			if line.Op != Equal {
+				changed++
+			}
+		}
+		diff := formatDiff(entry.Diff)
+		fence := markdownFence(diff)
+		fmt.Fprintf(&b, "<details><summary>%d lines changed</summary>\n\n%sdiff\n%s%s\n\n</details>\n\n", changed, fence, diff, fence)
+	}
+	return os.WriteFile(filePath, append([]byte(b.String()), existing...), 0644)
+}
+
+// markdownFence is a code fence longer than any run of backticks in text, so that the text can't close it.
+func markdownFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ChangeRecord is what kind of change a new version of the content is, as told by its diff with the previous one.
+type ChangeRecord struct {
+	// "added" when lines were only added, "removed" when they were only removed, "modified" otherwise.
+	Kind string `json:"kind"`
+	// Whether some lines changed only by the numbers in them, ex. a fee or a rate limit.
+	NumericChange bool `json:"numericChange,omitempty"`
+	// Those numbers, in the order they appear.
+	Numbers []NumberChange `json:"numbers,omitempty"`
+}
+
+type NumberChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// The new version of the line the number is in.
+	Line string `json:"line"`
+}
+
+var numberPattern = regexp.MustCompile(`-?\d+(?:[.,]\d+)*`)
+
+// Numeric changes listed in a notification's message; the record has all of them.
+const maxNumbersInMessage = 5
+
+// classifyChange compares two versions of the content. Removed and added lines next to each other are paired up in order,
+// and pairs that are the same once their numbers are taken out count as numeric changes.
+func classifyChange(old, new string) ChangeRecord {
+	var record ChangeRecord
+	var added, removed bool
+	var deleted, inserted []string
+	pairUp := func() {
+		for i := 0; i < len(deleted) && i < len(inserted); i++ {
+			record.Numbers = append(record.Numbers, numberChanges(deleted[i], inserted[i])...)
+		}
+		deleted, inserted = nil, nil
+	}
+	for _, line := range diffLines(old, new) {
+		switch line.Op {
+		case Delete:
+			removed = true
+			if len(inserted) > 0 {
+				pairUp()
+			}
+			deleted = append(deleted, line.Text)
+		case Insert:
+			added = true
+			inserted = append(inserted, line.Text)
+		default:
+			pairUp()
+		}
+	}
+	pairUp()
+
+	switch {
+	case added && !removed:
+		record.Kind = "added"
+	case removed && !added:
+		record.Kind = "removed"
+	default:
+		record.Kind = "modified"
+	}
+	record.NumericChange = len(record.Numbers) > 0
+	return record
+}
+
+// numberChanges returns the numbers that differ between the two lines, if that's the only difference between them.
+func numberChanges(before, after string) []NumberChange {
+	if numberPattern.ReplaceAllString(before, "#") != numberPattern.ReplaceAllString(after, "#") {
+		return nil
+	}
+	beforeNumbers, afterNumbers := numberPattern.FindAllString(before, -1), numberPattern.FindAllString(after, -1)
+	var changes []NumberChange
+	for i := range beforeNumbers {
+		if beforeNumbers[i] != afterNumbers[i] {
+			changes = append(changes, NumberChange{Before: beforeNumbers[i], After: afterNumbers[i], Line: strings.TrimSpace(after)})
+		}
+	}
+	return changes
+}
+
+// summary is the numeric changes as lines of a notification, ex. `1200 -> 600 in "Rate limit: 600/min"`.
+func (r ChangeRecord) summary() string {
+	if !r.NumericChange {
+		return ""
+	}
+	lines := make([]string, 0, min(len(r.Numbers), maxNumbersInMessage)+1)
+	for _, number := range r.Numbers[:min(len(r.Numbers), maxNumbersInMessage)] {
+		lines = append(lines, fmt.Sprintf("%s -> %s in %q", number.Before, number.After, number.Line))
+	}
+	if len(r.Numbers) > maxNumbersInMessage {
+		lines = append(lines, fmt.Sprintf("and %d more numbers", len(r.Numbers)-maxNumbersInMessage))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// FetchOptions are the per-target settings of how a page is fetched.
+type FetchOptions struct {
+	// Same as curl's --resolve: "host:port:addr".
+	Resolve string
+	// "host:port" of a SOCKS5 proxy to go through, ex. Tor.
+	SOCKS5 string
+	// "host:port" of the DNS server to resolve hostnames with instead of the system's, see dnsServerAddress.
+	DNSServer string
+	// How many redirects to follow before giving up, 0 to not follow any.
+	MaxRedirects int
+	// Follow <meta http-equiv="refresh"> redirects too, up to maxMetaRefreshes of them.
+	FollowMetaRefresh bool
+	// Leave the url as is, without the nocache parameter.
+	NoCacheBuster bool
+	// Accept header to send, to content-negotiate ex. json. Empty for defaultAccept.
+	Accept string
+	// Validators of the last check to make the request conditional on, with --conditional. A 304 then comes back as a Page that is NotModified.
+	IfNoneMatch     string
+	IfModifiedSince string
+	// Range header to send, ex. "bytes=0-100000", to download only part of a huge page, see rangeBody.
+	Range string
+	// Parse the response as json or yaml whatever its Content-Type, for apis that serve it as text/plain.
+	JSON bool
+	YAML bool
+	// Statuses to take the content of, see StatusList.
+	AcceptStatus StatusList
+	// Accept any certificate, ex. an expired one, when it's known to be broken but the content still matters.
+	InsecureSkipVerify bool
+
+	// Connection pool tuning, the same for every target of a run. 0 leaves Go's defaults.
+	MaxIdleConns    int
+	MaxConnsPerHost int
+	// Stick to HTTP/1.1, for hosts that misbehave on HTTP/2.
+	DisableHTTP2 bool
+	// Give up on a response that went this long without sending a single byte, even if it's still trickling in overall. 0 for no limit.
+	ReadStallTimeout time.Duration
+}
+
+var (
+	transportsMu sync.Mutex
+	// Transports are shared by every fetch with the same options, so that connections to a host are kept alive and reused across targets.
+	transports = make(map[FetchOptions]*http.Transport)
+)
+
+// newClient returns the client to fetch a target with. When resolve is set, connections to its host:port are dialed to the given address instead,
+// so the request still carries the original Host header and TLS SNI.
+// Through SOCKS5, hostnames are resolved by the proxy, which is what makes .onion addresses work.
+func newClient(opts FetchOptions) (*http.Client, error) {
+	// Redirects, headers and stalls are up to the client and request, transports are the same regardless.
+	key := opts
+	key.MaxRedirects, key.FollowMetaRefresh, key.NoCacheBuster, key.Accept, key.JSON, key.YAML, key.AcceptStatus, key.ReadStallTimeout = 0, false, false, "", false, false, "", 0
+	key.IfNoneMatch, key.IfModifiedSince, key.Range = "", "", ""
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transport, ok := transports[key]
+	if !ok {
+		var err error
+		transport, err = newTransport(opts)
+		if err != nil {
+			return nil, err
+		}
+		transports[key] = transport
+	}
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect(opts.MaxRedirects)}, nil
+}
+
+func newTransport(opts FetchOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = opts.MaxConnsPerHost
+		// Otherwise only 2 of them are kept alive in between requests.
+		transport.MaxIdleConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		// A non-nil empty map is what turns HTTP/2 off for good.
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	if opts.Resolve == "" && opts.SOCKS5 == "" && opts.DNSServer == "" && !opts.InsecureSkipVerify {
+		return transport, nil
+	}
+
+	direct := &net.Dialer{}
+	if opts.DNSServer != "" {
+		server, err := dnsServerAddress(opts.DNSServer)
+		if err != nil {
+			return nil, err
+		}
+		direct.Resolver = &net.Resolver{
+			// The cgo resolver would go by resolv.conf regardless.
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: dnsTimeout}).DialContext(ctx, network, server)
+			},
+		}
+	}
+	var dialer proxy.ContextDialer = direct
+	if opts.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if opts.SOCKS5 != "" {
+		// Only the proxy's own hostname is resolved with the DNS server, the ones of the pages are up to the proxy.
+		socksDialer, err := proxy.SOCKS5("tcp", opts.SOCKS5, nil, direct)
+		if err != nil {
+			return nil, fmt.Errorf("invalid socks5 proxy %s: %w", opts.SOCKS5, err)
+		}
+		dialer = socksDialer.(proxy.ContextDialer)
+		transport.Proxy = nil
+	}
+
+	if opts.Resolve != "" {
+		parts := strings.SplitN(opts.Resolve, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid resolve override, expected format 'host:port:addr', got: %s", opts.Resolve)
+		}
+		from := net.JoinHostPort(parts[0], parts[1])
+		to := net.JoinHostPort(strings.Trim(parts[2], "[]"), parts[1])
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == from {
+				addr = to
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	} else {
+		transport.DialContext = dialer.DialContext
+	}
+	return transport, nil
+}
+
+// How long to wait for the DNS server to answer, before the resolver tries again.
+const dnsTimeout = 5 * time.Second
+
+// dnsServerAddress is the "host:port" of a DNS server given as "1.1.1.1", "1.1.1.1:53", "2606:4700::1111" or "[2606:4700::1111]:53", port 53 by default.
+func dnsServerAddress(server string) (string, error) {
+	if host, port, err := net.SplitHostPort(server); err == nil {
+		if host == "" || port == "" {
+			return "", fmt.Errorf("invalid DNS server, expected an address like '1.1.1.1' or '1.1.1.1:53', got: %s", server)
+		}
+		return server, nil
+	}
+	if server == "" || strings.ContainsAny(server, "[]") || (strings.Contains(server, ":") && net.ParseIP(server) == nil) {
+		return "", fmt.Errorf("invalid DNS server, expected an address like '1.1.1.1' or '1.1.1.1:53', got: %s", server)
+	}
+	return net.JoinHostPort(server, "53"), nil
+}
+
+// StatusList is the HTTP statuses responses are taken as successful with, comma-separated in ascending order, ex. "200,203".
+// It's a string rather than a slice so that FetchOptions stay comparable. Empty is 200 alone.
+type StatusList string
+
+// Statuses the client follows as redirects, which never get to be accepted.
+var redirectStatuses = []int{http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect}
+
+func parseStatusList(s string) (StatusList, error) {
+	var codes []int
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 599 {
+			return "", fmt.Errorf("expected HTTP statuses like '200,203', got %q", field)
+		}
+		if slices.Contains(redirectStatuses, code) {
+			return "", fmt.Errorf("%d is a redirect, which is followed up to --max-redirects rather than accepted", code)
+		}
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return "", fmt.Errorf("expected at least one HTTP status")
+	}
+	slices.Sort(codes)
+	fields := make([]string, len(codes))
+	for i, code := range codes {
+		fields[i] = strconv.Itoa(code)
+	}
+	return StatusList(strings.Join(fields, ",")), nil
+}
+
+func (l StatusList) Accepts(code int) bool {
+	if l == "" {
+		return code == http.StatusOK
+	}
+	return slices.Contains(strings.Split(string(l), ","), strconv.Itoa(code))
+}
+
+// checkRedirect stops after maxRedirects, and as soon as the chain comes back to a url it has already been through, instead of going round in circles until the limit.
+func checkRedirect(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		for _, previous := range via {
+			if withoutCacheBuster(previous.URL) == withoutCacheBuster(req.URL) {
+				chain := make([]string, 0, len(via)+1)
+				for _, r := range via {
+					chain = append(chain, withoutCacheBuster(r.URL))
+				}
+				chain = append(chain, withoutCacheBuster(req.URL))
+				return fmt.Errorf("redirect loop detected: %s", strings.Join(chain, " -> "))
+			}
+		}
+		if maxRedirects == 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+}
+
+// withCacheBuster adds a random nocache parameter to rawURL, to bypass Cloudflare's cache. It goes after the page's own parameters,
+// which are left as they are, and before the fragment.
+func withCacheBuster(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	buster := url.Values{"nocache": {strconv.Itoa(rand.Intn(1000000))}}.Encode()
+	if u.RawQuery == "" {
+		u.RawQuery = buster
+	} else {
+		u.RawQuery += "&" + buster
+	}
+	return u.String(), nil
+}
+
+// withoutCacheBuster is u without the nocache parameter that get adds, which would otherwise make the first url of a redirect chain unique.
+// The page's own parameters are left as they are, in their order and encoding, as some servers care.
+func withoutCacheBuster(u *url.URL) string {
+	stripped := *u
+	var params []string
+	for _, param := range strings.Split(stripped.RawQuery, "&") {
+		if param != "" && param != "nocache" && !strings.HasPrefix(param, "nocache=") {
+			params = append(params, param)
+		}
+	}
+	stripped.RawQuery = strings.Join(params, "&")
+	stripped.ForceQuery = false
+	return stripped.String()
+}
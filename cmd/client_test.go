@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestWithCacheBuster(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		// What the url should be, with N in place of the random number.
+		want string
+	}{
+		{"no query", "https://example.com/docs", "https://example.com/docs?nocache=N"},
+		{"query", "https://example.com/docs?b=2&a=1", "https://example.com/docs?b=2&a=1&nocache=N"},
+		{"encoded query", "https://example.com/docs?q=a%20b&x=%2F", "https://example.com/docs?q=a%20b&x=%2F&nocache=N"},
+		{"fragment", "https://example.com/docs#limits", "https://example.com/docs?nocache=N#limits"},
+		{"query and fragment", "https://example.com/docs?v=3&lang=en#limits", "https://example.com/docs?v=3&lang=en&nocache=N#limits"},
+	}
+	number := regexp.MustCompile(`nocache=\d+`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			busted, err := withCacheBuster(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := number.ReplaceAllString(busted, "nocache=N"); got != tt.want {
+				t.Errorf("withCacheBuster(%q) = %q, want %q", tt.url, busted, tt.want)
+			}
+			u, err := url.Parse(busted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := withoutCacheBuster(u); got != tt.url {
+				t.Errorf("withoutCacheBuster(%q) = %q, want %q back", busted, got, tt.url)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+)
+
+// compareURLs extracts --selector from two urls and diffs them, ex. the staging and production versions of the same docs. Nothing is read from or saved to the hashes file.
+func compareURLs(c *cli.Context) error {
+	urlA, urlB, htmlClass := c.String("url-a"), c.String("url-b"), c.String("selector")
+	if urlA == "" || urlB == "" || htmlClass == "" {
+		return fmt.Errorf("--url-a, --url-b and --selector are required")
+	}
+	githubToken, err := resolveSecret(c.String("github-token"))
+	if err != nil {
+		return fmt.Errorf("--github-token: %w", err)
+	}
+	args := RunArgs{
+		SelectorType:      c.String("selector-type"),
+		RawText:           c.Bool("raw-text"),
+		SOCKS5:            c.String("socks5"),
+		DNSServer:         c.String("dns-server"),
+		MaxRedirects:      c.Int("max-redirects"),
+		FollowMetaRefresh: c.Bool("follow-meta-refresh"),
+		InsecureHosts:     insecureHosts(c),
+		GitHubToken:       githubToken,
+		Docs:              NewDocCache(0, 0),
+	}
+	if args.SelectorType != "css" && args.SelectorType != "xpath" {
+		return fmt.Errorf("selector type must be 'css' or 'xpath', got: %s", args.SelectorType)
+	}
+
+	urls := []string{urlA, urlB}
+	contents := make([]string, len(urls))
+	var g errgroup.Group
+	for i, url := range urls {
+		g.Go(func() error {
+			entry := &Entry{}
+			target, err := newTarget(joinKey(url, htmlClass), entry)
+			if err != nil {
+				return err
+			}
+			contents[i], _, err = args.fetcherFor(target).Fetch(context.Background(), target)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	if contents[0] == contents[1] {
+		fmt.Printf("%s and %s match (%s)\n", urlA, urlB, htmlClass)
+		return nil
+	}
+	fmt.Printf("--- %s\n+++ %s\n", urlA, urlB)
+	fmt.Print(formatDiff(diffLines(contents[0], contents[1])))
+	size := measureDiff(contents[0], contents[1])
+	return fmt.Errorf("%s and %s differ by %d lines", urlA, urlB, size.Lines)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// Config is the optional --config file, for settings that aren't tied to a single target.
+type Config struct {
+	// Hashes file to use when --path isn't given.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Snapshot directory to use when --snapshot-dir isn't given.
+	SnapshotDir string `json:"snapshotDir,omitempty" yaml:"snapshotDir,omitempty"`
+	// Targets pick one of these by name with their `notify` field.
+	Notifiers map[string]NotifierConfig `json:"notifiers,omitempty" yaml:"notifiers,omitempty"`
+	// Notifier to also send the notifications of a severity to, by severity ("info", "warn" or "critical"), ex. critical ones to the on-call.
+	// Severities without a route go to the route of the one below them.
+	SeverityRoutes map[string]string `json:"severityRoutes,omitempty" yaml:"severityRoutes,omitempty"`
+	// Targets to add for every combination of values of their variables, ex. the same docs on every regional domain.
+	Templates []TargetTemplate `json:"templates,omitempty" yaml:"templates,omitempty"`
+	// Go text/template templates of the notifications' messages by event, see MessageData, ex. {"changed": "{{.ID}} changed: {{.URL}}"}.
+	// Events without a template keep the default wording.
+	Messages map[string]string `json:"messages,omitempty" yaml:"messages,omitempty"`
+	// Environments like "staging", picked with --env. Their settings override the ones above, notifiers are merged by name.
+	Profiles map[string]Config `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// TargetTemplate is a url with {var} placeholders, like "https://{region}.example.com/docs", expanded into a target per combination of the values of its vars.
+// The targets share the selector and options.
+type TargetTemplate struct {
+	URL      string              `json:"url" yaml:"url"`
+	Selector string              `json:"selector" yaml:"selector"`
+	Vars     map[string][]string `json:"vars" yaml:"vars"`
+	// Per-target options of the hashes file, like `notify` or `priority`.
+	Options *Entry `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// expand returns the urls of every combination of the values of the vars, in order.
+func (t TargetTemplate) expand() []string {
+	urls := []string{t.URL}
+	names := make([]string, 0, len(t.Vars))
+	for name := range t.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var expanded []string
+		for _, u := range urls {
+			for _, value := range t.Vars[name] {
+				expanded = append(expanded, strings.ReplaceAll(u, "{"+name+"}", value))
+			}
+		}
+		urls = expanded
+	}
+	return urls
+}
+
+func (t TargetTemplate) validate() error {
+	if t.URL == "" || t.Selector == "" {
+		return fmt.Errorf("url and selector are required")
+	}
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(t.URL, -1) {
+		if _, ok := t.Vars[match[1]]; !ok {
+			return fmt.Errorf("{%s} has no values in vars", match[1])
+		}
+	}
+	names := make([]string, 0, len(t.Vars))
+	for name := range t.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !strings.Contains(t.URL, "{"+name+"}") {
+			return fmt.Errorf("var %q isn't used in the url", name)
+		}
+		if len(t.Vars[name]) == 0 {
+			return fmt.Errorf("var %q has no values", name)
+		}
+	}
+	if t.Options != nil {
+		if t.Options.Hash != "" {
+			return fmt.Errorf("options: the hash is up to the checks, not the template")
+		}
+		if err := t.Options.validate(); err != nil {
+			return fmt.Errorf("options: %w", err)
+		}
+	}
+	return nil
+}
+
+// templateTargets are the targets the templates expand into, with their options and no hash yet.
+func (c Config) templateTargets() Hashes {
+	targets := make(Hashes)
+	for _, template := range c.Templates {
+		for _, url := range template.expand() {
+			entry := &Entry{}
+			if template.Options != nil {
+				copied := *template.Options
+				entry = &copied
+			}
+			targets[joinKey(url, template.Selector)] = entry
+		}
+	}
+	return targets
+}
+
+// withProfile is the config as seen by the named profile: its settings over the defaults. An empty name is the defaults alone.
+func (c Config) withProfile(name string) (Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return c, fmt.Errorf("no profile %q in the config, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	merged := Config{
+		Path:           c.Path,
+		SnapshotDir:    c.SnapshotDir,
+		Notifiers:      make(map[string]NotifierConfig, len(c.Notifiers)+len(profile.Notifiers)),
+		SeverityRoutes: make(map[string]string, len(c.SeverityRoutes)+len(profile.SeverityRoutes)),
+		Messages:       make(map[string]string, len(c.Messages)+len(profile.Messages)),
+		// A profile's templates come on top of the default ones.
+		Templates: append(slices.Clip(c.Templates), profile.Templates...),
+	}
+	if profile.Path != "" {
+		merged.Path = profile.Path
+	}
+	if profile.SnapshotDir != "" {
+		merged.SnapshotDir = profile.SnapshotDir
+	}
+	for name, notifier := range c.Notifiers {
+		merged.Notifiers[name] = notifier
+	}
+	for name, notifier := range profile.Notifiers {
+		merged.Notifiers[name] = notifier
+	}
+	for severity, name := range c.SeverityRoutes {
+		merged.SeverityRoutes[severity] = name
+	}
+	for severity, name := range profile.SeverityRoutes {
+		merged.SeverityRoutes[severity] = name
+	}
+	for event, message := range c.Messages {
+		merged.Messages[event] = message
+	}
+	for event, message := range profile.Messages {
+		merged.Messages[event] = message
+	}
+	return merged, nil
+}
+
+// EffectiveConfig is what --print-config shows: every setting of the run as it was resolved, flag defaults included.
+type EffectiveConfig struct {
+	Flags      map[string]any `json:"flags" yaml:"flags"`
+	HashesPath string         `json:"hashesPath" yaml:"hashesPath"`
+	ConfigPath string         `json:"configPath,omitempty" yaml:"configPath,omitempty"`
+	Config     Config         `json:"config" yaml:"config"`
+}
+
+const redacted = "REDACTED"
+
+// newEffectiveConfig collects the values of all the flags of the command, with the secrets in them and in config redacted.
+func newEffectiveConfig(c *cli.Context, hashesPath, configPath string, config Config) EffectiveConfig {
+	effective := EffectiveConfig{
+		Flags:      make(map[string]any),
+		HashesPath: hashesPath,
+		ConfigPath: configPath,
+		Config: Config{
+			Path:           config.Path,
+			SnapshotDir:    config.SnapshotDir,
+			Templates:      config.Templates,
+			SeverityRoutes: config.SeverityRoutes,
+			Messages:       config.Messages,
+			Notifiers:      make(map[string]NotifierConfig, len(config.Notifiers)),
+		},
+	}
+	for _, flag := range c.Command.Flags {
+		name := flag.GetName()
+		if name == "print-config" || name == cli.HelpFlag.GetName() {
+			continue
+		}
+		switch flag.(type) {
+		case *cli.BoolFlag:
+			effective.Flags[name] = c.Bool(name)
+		case *cli.IntFlag:
+			effective.Flags[name] = c.Int(name)
+		case *cli.Float64Flag:
+			effective.Flags[name] = c.Float64(name)
+		case *cli.DurationFlag:
+			effective.Flags[name] = c.Duration(name).String()
+		default:
+			effective.Flags[name] = c.String(name)
+		}
+	}
+	if telegram, ok := effective.Flags["telegram"].(string); ok {
+		effective.Flags["telegram"] = redactTelegram(telegram)
+	}
+	for name, notifier := range config.Notifiers {
+		notifier.Telegram = redactTelegram(notifier.Telegram)
+		notifier.Slack = redactURLPath(notifier.Slack)
+		effective.Config.Notifiers[name] = notifier
+	}
+	return effective
+}
+
+// redactTelegram hides the bot token of 'token,chatID', unless it's only a reference to where the token is, like 'env:VAR'.
+func redactTelegram(value string) string {
+	token, chatID, ok := strings.Cut(value, ",")
+	if !ok || strings.HasPrefix(token, "env:") || strings.HasPrefix(token, "file:") {
+		return value
+	}
+	return redacted + "," + chatID
+}
+
+// redactURLPath hides the path of a webhook url, which is what authorizes posting to it.
+func redactURLPath(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return value
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+func loadConfig(filePath string) (Config, error) {
+	var config Config
+	if filePath == "" {
+		return config, nil
+	}
+	file, err := os.ReadFile(filePath)
+	if err != nil {
+		return config, err
+	}
+	// Unknown fields are rejected, so that a typo doesn't silently turn a setting off.
+	decoder := json.NewDecoder(bytes.NewReader(file))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to parse config %s%s: %w", filePath, jsonErrorPosition(file, err), err)
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("invalid config %s:\n%w", filePath, err)
+	}
+	return config, nil
+}
+
+func (c Config) validate() error {
+	names := make([]string, 0, len(c.Notifiers))
+	for name := range c.Notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := c.Notifiers[name].validate(); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %q: %w", name, err))
+		}
+	}
+	severities := make([]string, 0, len(c.SeverityRoutes))
+	for severity := range c.SeverityRoutes {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+	for _, severity := range severities {
+		if _, err := parseSeverity(severity); err != nil {
+			errs = append(errs, fmt.Errorf("severityRoutes: severity %w", err))
+		}
+	}
+	if _, err := parseMessageTemplates(c.Messages); err != nil {
+		errs = append(errs, fmt.Errorf("messages: %w", err))
+	}
+	for i, template := range c.Templates {
+		if err := template.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("template %d (%s): %w", i+1, template.URL, err))
+		}
+	}
+
+	profiles := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	for _, name := range profiles {
+		profile := c.Profiles[name]
+		if len(profile.Profiles) > 0 {
+			errs = append(errs, fmt.Errorf("profile %q: can't have profiles of its own", name))
+			continue
+		}
+		if err := profile.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("profile %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c NotifierConfig) validate() error {
+	if c.Telegram == "" && c.Slack == "" && c.Command == "" && c.Ntfy == "" {
+		return fmt.Errorf("sends nowhere, set at least one of telegram, slack, command or ntfy")
+	}
+	if c.Telegram != "" {
+		if _, err := NewTgArgs(c.Telegram); err != nil {
+			return fmt.Errorf("telegram: %w", err)
+		}
+	}
+	if c.Slack != "" {
+		if u, err := url.Parse(c.Slack); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("slack: expected a webhook url, got %q", c.Slack)
+		}
+	}
+	if c.Ntfy != "" {
+		if err := (NtfyNotifier{TopicURL: c.Ntfy, Priority: c.NtfyPriority, Tags: c.NtfyTags}).validate(); err != nil {
+			return fmt.Errorf("ntfy: %w", err)
+		}
+	} else if c.NtfyPriority != "" || c.NtfyTags != "" {
+		return fmt.Errorf("ntfyPriority and ntfyTags need ntfy")
+	}
+	return nil
+}
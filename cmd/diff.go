@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type DiffOp int
+
+const (
+	Equal DiffOp = iota
+	Insert
+	Delete
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// diffLines is a line diff of old and new, by Myers' algorithm.
+func diffLines(old, new string) []DiffLine {
+	a, b := splitLines(old), splitLines(new)
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD == 0 {
+		return nil
+	}
+	offset := maxD
+	v := make([]int, 2*maxD+2)
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []DiffLine {
+	var lines []DiffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, DiffLine{Op: Equal, Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			lines = append(lines, DiffLine{Op: Insert, Text: b[y]})
+		} else {
+			x--
+			lines = append(lines, DiffLine{Op: Delete, Text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		lines = append(lines, DiffLine{Op: Equal, Text: a[x]})
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// printDiff writes how the content of a target changed since its last snapshot, in a single write so that concurrent checks don't interleave.
+func printDiff(w io.Writer, id, url, htmlClass, previous string, hasPrevious bool, content string) {
+	if !hasPrevious {
+		fmt.Fprintf(w, "[%s] %s (%s) changed, with no earlier snapshot to diff against\n", id, url, htmlClass)
+		return
+	}
+	fmt.Fprintf(w, "[%s] %s (%s) changed:\n%s", id, url, htmlClass, formatDiff(diffLines(previous, content)))
+}
+
+// Lines of unchanged content shown around each change by formatDiff.
+const diffContext = 2
+
+// formatDiff writes the changed lines of diff prefixed with "+ " and "- ", along with a few unchanged lines around them, with "..." between changes far apart.
+func formatDiff(diff []DiffLine) string {
+	var b strings.Builder
+	printed := -1
+	for i, line := range diff {
+		if line.Op == Equal {
+			continue
+		}
+		start := max(i-diffContext, printed+1)
+		if printed >= 0 && start > printed+1 {
+			b.WriteString("...\n")
+		}
+		for _, unchanged := range diff[start:i] {
+			fmt.Fprintf(&b, "  %s\n", unchanged.Text)
+		}
+		if line.Op == Insert {
+			fmt.Fprintf(&b, "+ %s\n", line.Text)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", line.Text)
+		}
+		printed = i
+		// Context after the change, up to the next one.
+		for printed+1 < len(diff) && printed+1 <= i+diffContext && diff[printed+1].Op == Equal {
+			printed++
+			fmt.Fprintf(&b, "  %s\n", diff[printed].Text)
+		}
+	}
+	return b.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// DiffSize is how big a change between two versions of some content is.
+type DiffSize struct {
+	// Lines added plus lines removed.
+	Lines int `json:"lines" yaml:"lines"`
+	// Length of the part that differs, once the common beginning and end are taken away.
+	Chars int `json:"chars" yaml:"chars"`
+}
+
+func measureDiff(old, new string) DiffSize {
+	var size DiffSize
+	for _, line := range diffLines(old, new) {
+		if line.Op != Equal {
+			size.Lines++
+		}
+	}
+
+	oldRunes, newRunes := []rune(old), []rune(new)
+	prefix := 0
+	for prefix < len(oldRunes) && prefix < len(newRunes) && oldRunes[prefix] == newRunes[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldRunes)-prefix && suffix < len(newRunes)-prefix && oldRunes[len(oldRunes)-1-suffix] == newRunes[len(newRunes)-1-suffix] {
+		suffix++
+	}
+	size.Chars = max(len(oldRunes), len(newRunes)) - prefix - suffix
+	return size
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+// matchTargets returns the keys tracking url, only the one with htmlClass if it's given.
+func matchTargets(hashes Hashes, url, htmlClass string) []string {
+	var keys []string
+	for key := range hashes {
+		keyURL, keyHTMLClass, err := splitKey(key)
+		if err != nil || keyURL != url || (htmlClass != "" && keyHTMLClass != htmlClass) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func setEnabled(c *cli.Context, enabled bool) error {
+	url := c.String("url")
+	if url == "" {
+		return fmt.Errorf("--url is required")
+	}
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	keys := matchTargets(hashes, url, c.String("selector"))
+	if len(keys) == 0 {
+		return fmt.Errorf("no targets track %s", url)
+	}
+	for _, key := range keys {
+		if enabled {
+			// Enabled is the default, no need to spell it out in the file.
+			hashes[key].Enabled = nil
+		} else {
+			hashes[key].Enabled = &enabled
+		}
+	}
+	if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+		return err
+	}
+	state := "Disabled"
+	if enabled {
+		state = "Enabled"
+	}
+	fmt.Printf("%s %d targets of %s\n", state, len(keys), url)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Valera6/doc_scraper/pkg/checker"
+)
+
+// Event is a single line of the --events-file.
+type Event struct {
+	checker.ChangeEvent
+	// For changes with a snapshot to compare with, their kind and changed numbers, inline.
+	*ChangeRecord
+}
+
+// EventLog appends events to a jsonl file as they happen. Each event is written straight to the file, so nothing is lost if the run dies midway.
+// A nil *EventLog discards everything.
+//
+// With "-" for the file, events are streamed to stdout instead, for another process to react to each change as soon as it's found.
+// Writes are synchronous: a consumer that doesn't keep up holds the run back rather than events being buffered or dropped.
+type EventLog struct {
+	mu   sync.Mutex
+	file *os.File
+	// Whether file is stdout, which isn't closed.
+	stdout bool
+}
+
+func OpenEventLog(filePath string) (*EventLog, error) {
+	if filePath == "-" {
+		return &EventLog{file: os.Stdout, stdout: true}, nil
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLog{file: file}, nil
+}
+
+func (l *EventLog) Record(key, id string, status Status, oldHash, newHash string, change *ChangeRecord, checkErr error) {
+	if l == nil {
+		return
+	}
+	event := Event{ChangeEvent: checker.ChangeEvent{
+		Timestamp: time.Now(),
+		ID:        id,
+		OldHash:   oldHash,
+		NewHash:   newHash,
+	}}
+	event.URL, event.Selector, _ = splitKey(key)
+	switch {
+	case checkErr != nil:
+		event.Event = checker.Error
+		event.NewHash = ""
+		event.Error = checkErr.Error()
+	case status == Changed || status == Minor:
+		event.Event = checker.Changed
+		event.Minor = status == Minor
+		event.ChangeRecord = change
+	case status == Unchanged:
+		event.Event = checker.Unchanged
+	default:
+		return
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.file.Write(append(line, '\n'))
+}
+
+func (l *EventLog) Close() error {
+	if l == nil || l.file == nil || l.stdout {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Lines stay flat objects, with the fields of the change inline, as consumers of the --events-file parse them.
+func TestEventLogRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "events.jsonl")
+	events, err := OpenEventLog(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := joinKey("https://example.com/docs", "h1")
+	events.Record(key, "docs", Changed, "old", "new", &ChangeRecord{Kind: "added"}, nil)
+	events.Record(key, "docs", Skipped, "new", "new", nil, nil)
+	if err := events.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(file), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("%d lines, want 1 as skipped targets have no event:\n%s", len(lines), file)
+	}
+	// Without the timestamp, which varies.
+	line := lines[0][bytes.Index(lines[0], []byte(`,"id"`)):]
+	want := `,"id":"docs","url":"https://example.com/docs","selector":"h1","event":"changed","oldHash":"old","newHash":"new","kind":"added"}`
+	if string(line) != want {
+		t.Errorf("line = %s, want {\"timestamp\":...%s", lines[0], want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/Valera6/doc_scraper/pkg/render"
+)
+
+// Extraction is how to get the content to hash out of a page.
+type Extraction struct {
+	// "css" or "xpath".
+	SelectorType string
+	// Take the text nodes exactly as they are in the source, concatenated, instead of rendering them with render.RenderText.
+	RawText bool
+	// Sort the items of the content, so their order doesn't matter. Items are what ItemSelector matches, or lines if it's empty.
+	OrderInsensitive bool
+	ItemSelector     string
+	// Narrow the content down to what this matches, see applyRegex.
+	Regex string
+	// Applied to the text in order, before anything else; see textTransforms.
+	Transforms []string
+	// When either is set, the content is the text of the whole page between them instead of what the selector matches, see cutAnchors.
+	StartAnchor string
+	EndAnchor   string
+	// Only the match at this index, from 0, is taken instead of all of them. See selectMatches.
+	MatchIndex *int
+}
+
+func (e Extraction) anchored() bool {
+	return e.StartAnchor != "" || e.EndAnchor != ""
+}
+
+// textTransforms are what a target's `transforms` can list, to ignore variations that don't change the meaning of the text.
+var textTransforms = map[string]func(string) string{
+	"lowercase": strings.ToLower,
+	// Same characters encoded differently, ex. "é" as a single code point or as "e" followed by a combining accent, become the same.
+	"nfc": norm.NFC.String,
+}
+
+func transformText(text string, transforms []string) string {
+	for _, name := range transforms {
+		text = textTransforms[name](text)
+	}
+	return text
+}
+
+// extractContent returns the text of everything htmlClass matches in the document.
+func extractContent(doc *goquery.Document, htmlClass string, extraction Extraction) (string, error) {
+	nodes := doc.Nodes
+	if !extraction.anchored() {
+		var err error
+		nodes, err = selectMatches(doc, htmlClass, extraction)
+		if err != nil {
+			return "", err
+		}
+	}
+	text := transformText(nodesText(nodes, extraction.RawText), extraction.Transforms)
+	text, err := cutAnchors(text, extraction.StartAnchor, extraction.EndAnchor)
+	if err != nil {
+		return "", err
+	}
+	if !extraction.OrderInsensitive {
+		return applyRegex(text, extraction.Regex)
+	}
+
+	var items []string
+	if extraction.ItemSelector != "" {
+		doc.FindNodes(nodes...).Find(extraction.ItemSelector).Each(func(i int, s *goquery.Selection) {
+			items = append(items, transformText(nodesText(s.Nodes, extraction.RawText), extraction.Transforms))
+		})
+	} else {
+		items = strings.Split(text, "\n")
+	}
+	sort.Strings(items)
+	return applyRegex(strings.Join(items, "\n"), extraction.Regex)
+}
+
+// cutAnchors returns the text between the first occurrence of start and the first occurrence of end after it, both left out.
+// An empty start is the beginning of the text, an empty end its end. Either missing from the text is an error, rather than content that
+// would look like everything was removed.
+func cutAnchors(text, start, end string) (string, error) {
+	if start != "" {
+		i := strings.Index(text, start)
+		if i < 0 {
+			return "", fmt.Errorf("startAnchor %q isn't on the page", start)
+		}
+		text = text[i+len(start):]
+	}
+	if end != "" {
+		i := strings.Index(text, end)
+		if i < 0 {
+			return "", fmt.Errorf("endAnchor %q isn't on the page after startAnchor %q", end, start)
+		}
+		text = text[:i]
+	}
+	return strings.Trim(text, "\n"), nil
+}
+
+// applyRegex returns what pattern captures in text: its first group if it has any, otherwise the whole match.
+// Every match is kept, one per line, so a value showing up or disappearing elsewhere is still a change. Text is returned as is if pattern is empty.
+func applyRegex(text, pattern string) (string, error) {
+	if pattern == "" {
+		return text, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	matches := re.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return "", fmt.Errorf("extractRegex %q matched nothing", pattern)
+	}
+	values := make([]string, len(matches))
+	for i, match := range matches {
+		values[i] = match[min(1, len(match)-1)]
+	}
+	return strings.Join(values, "\n"), nil
+}
+
+func selectNodes(doc *goquery.Document, htmlClass, selectorType string) ([]*html.Node, error) {
+	switch selectorType {
+	case "", "css":
+		return doc.Find(htmlClass).Nodes, nil
+	case "xpath":
+		return htmlquery.QueryAll(doc.Nodes[0], htmlClass)
+	default:
+		return nil, fmt.Errorf("unknown selector type: %s", selectorType)
+	}
+}
+
+// indexQualifier is a css selector ending in the index of the match to take, ex. "table.rates[1]". An attribute can't start with a digit,
+// so it can't be mistaken for part of the selector. Xpath has its own, 1-based, "(//table)[2]".
+var indexQualifier = regexp.MustCompile(`^(.*\S)\[(\d+)\]$`)
+
+// splitMatchIndex separates the index qualifier from a css selector, returning the selector as is if it has none.
+func splitMatchIndex(htmlClass, selectorType string) (string, *int) {
+	if selectorType == "xpath" {
+		return htmlClass, nil
+	}
+	groups := indexQualifier.FindStringSubmatch(htmlClass)
+	if groups == nil {
+		return htmlClass, nil
+	}
+	index, err := strconv.Atoi(groups[2])
+	if err != nil {
+		return htmlClass, nil
+	}
+	return groups[1], &index
+}
+
+// selectMatches is what the selector matches, narrowed down to a single element by an index qualifier or the extraction's MatchIndex,
+// for when the selector can't be made unique with css alone. An index past the matches is an error rather than empty content,
+// which would look like everything was removed.
+func selectMatches(doc *goquery.Document, htmlClass string, extraction Extraction) ([]*html.Node, error) {
+	selector, index := splitMatchIndex(htmlClass, extraction.SelectorType)
+	if extraction.MatchIndex != nil {
+		if index != nil {
+			return nil, fmt.Errorf("%s already ends with an index, matchIndex can't apply too", htmlClass)
+		}
+		index = extraction.MatchIndex
+	}
+	nodes, err := selectNodes(doc, selector, extraction.SelectorType)
+	if err != nil || index == nil {
+		return nodes, err
+	}
+	if *index >= len(nodes) {
+		return nil, fmt.Errorf("match index %d is out of range, %s matched %d elements", *index, selector, len(nodes))
+	}
+	return nodes[*index : *index+1], nil
+}
+
+func nodesText(nodes []*html.Node, rawText bool) string {
+	if !rawText {
+		return render.RenderText(nodes...)
+	}
+	content := ""
+	for _, node := range nodes {
+		content += render.RawText(node)
+	}
+	return content
+}
+
+// hashSelectors hashes the content under each selector of the group separately. Returns nil for anything that isn't a group of several selectors.
+func hashSelectors(doc *goquery.Document, htmlClass string, rawText bool) map[string]string {
+	group, err := cascadia.ParseGroup(htmlClass)
+	if err != nil || len(group) < 2 {
+		return nil
+	}
+	hashes := make(map[string]string, len(group))
+	for _, sel := range group {
+		content := nodesText(doc.Find(sel.String()).Nodes, rawText)
+		hashes[sel.String()] = getSHA256Hash(content)
+	}
+	return hashes
+}
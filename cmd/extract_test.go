@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestTransformTextNFC(t *testing.T) {
+	// "Café résumé" with é as a single code point, and as e followed by a combining acute accent.
+	composed, decomposed := "Café résumé", "Café résumé"
+	if composed == decomposed {
+		t.Fatal("the two forms should differ before the transform")
+	}
+	if getSHA256Hash(composed) == getSHA256Hash(decomposed) {
+		t.Fatal("the two forms should hash differently without the transform")
+	}
+	for _, transforms := range [][]string{{"nfc"}, {"nfc", "lowercase"}, {"lowercase", "nfc"}} {
+		a, b := transformText(composed, transforms), transformText(decomposed, transforms)
+		if getSHA256Hash(a) != getSHA256Hash(b) {
+			t.Errorf("with %v, %q and %q hash differently", transforms, a, b)
+		}
+	}
+}
+
+func TestTransformTextLowercase(t *testing.T) {
+	if got := transformText("Rate LIMITS", []string{"lowercase"}); got != "rate limits" {
+		t.Errorf("lowercase = %q, want %q", got, "rate limits")
+	}
+	if got := transformText("Rate LIMITS", nil); got != "Rate LIMITS" {
+		t.Errorf("no transforms = %q, want the text as is", got)
+	}
+}
+
+func TestApplyRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "no pattern", text: "Fee: 0.1%", pattern: "", want: "Fee: 0.1%"},
+		{name: "capture group", text: "Version 2.3.1 released", pattern: `Version (\d+(?:\.\d+)*)`, want: "2.3.1"},
+		{name: "whole match without a group", text: "Fee: 0.1%", pattern: `\d+(?:\.\d+)?%`, want: "0.1%"},
+		{name: "multiple matches", text: "Spot fee: 0.1%\nFutures fee: 0.02%\nOptions fee: 0.03%", pattern: `fee: ([\d.]+)%`, want: "0.1\n0.02\n0.03"},
+		{name: "no match", text: "Fees are listed elsewhere", pattern: `fee: ([\d.]+)%`, wantErr: true},
+		{name: "invalid pattern", text: "Fee: 0.1%", pattern: `fee: ([\d.]+%`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyRegex(tt.text, tt.pattern)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("applyRegex = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("applyRegex = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// runExtractor has an external program extract the content, for logic that selectors can't express. The command is run by the shell
+// with the url and selector as its arguments and the page's html on stdin, and whatever it prints on stdout is the content, as is.
+// Exiting with anything but 0 fails the check, with what it printed on stderr.
+func runExtractor(ctx context.Context, command string, timeout time.Duration, doc *goquery.Document, url, htmlClass string) (string, error) {
+	var page bytes.Buffer
+	if err := html.Render(&page, doc.Nodes[0]); err != nil {
+		return "", err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// "$@" passes the arguments on, so the command can have arguments of its own, ex. "python3 extract.py".
+	cmd := exec.CommandContext(ctx, "sh", "-c", command+` "$@"`, "extractor", url, htmlClass)
+	cmd.Stdin = &page
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			err = fmt.Errorf("%w: %s", err, output)
+		}
+		return "", fmt.Errorf("extractor %q failed on %s: %w", command, url, err)
+	}
+	return stdout.String(), nil
+}
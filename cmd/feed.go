@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// FeedItem is an entry of an RSS, Atom or JSON feed.
+type FeedItem struct {
+	// The item's guid (RSS) or id (Atom, JSON Feed), or its link if it has none.
+	ID    string
+	Title string
+	Link  string
+}
+
+// FeedFetcher gets an RSS, Atom or JSON feed. The content is its items, a title and a link per line, and its meta lists them so that only new items get notified of.
+// The selector of the target isn't used.
+type FeedFetcher struct {
+	Args RunArgs
+}
+
+func (f FeedFetcher) Fetch(ctx context.Context, target Target) (string, FetchMeta, error) {
+	args, url := f.Args, target.URL
+	var meta FetchMeta
+	release, err := args.Docs.Hosts.Acquire(ctx, url)
+	if err != nil {
+		return "", meta, err
+	}
+	defer release()
+	opts := args.fetchOptions(url, target.Entry)
+	resp, err := get(ctx, url, opts)
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to fetch content from %s: %w", url, classifyTLS(err))
+	}
+	defer resp.Body.Close()
+	if !opts.AcceptStatus.Accepts(resp.StatusCode) {
+		return "", meta, fmt.Errorf("failed to fetch content from %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	meta.Items, err = parseFeed(body)
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to parse the feed at %s: %w", url, err)
+	}
+
+	lines := make([]string, len(meta.Items))
+	for i, item := range meta.Items {
+		lines[i] = item.Title + " " + item.Link
+	}
+	return strings.Join(lines, "\n"), meta, nil
+}
+
+// parseFeed returns the items of an RSS (0.9x to 2.0, RDF included), Atom or JSON Feed feed, in the order they're listed. It's never nil, even for an empty feed.
+func parseFeed(data []byte) ([]FeedItem, error) {
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]FeedItem, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		items = append(items, newFeedItem(item.GUID, item.Title, item.Link))
+	}
+	return items, nil
+}
+
+// newFeedItem is the item with the given id, falling back on its link and then its title for feeds whose items have none, as is common with RSS.
+func newFeedItem(id, title, link string) FeedItem {
+	item := FeedItem{ID: strings.TrimSpace(id), Title: strings.TrimSpace(title), Link: strings.TrimSpace(link)}
+	if item.ID == "" {
+		item.ID = item.Link
+	}
+	if item.ID == "" {
+		item.ID = item.Title
+	}
+	return item
+}
+
+// newFeedItems returns the items that aren't among the seen ids, in feed order.
+func newFeedItems(seen []string, items []FeedItem) []FeedItem {
+	known := make(map[string]bool, len(seen))
+	for _, id := range seen {
+		known[id] = true
+	}
+	var fresh []FeedItem
+	for _, item := range items {
+		if !known[item.ID] {
+			fresh = append(fresh, item)
+		}
+	}
+	return fresh
+}
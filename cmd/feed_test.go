@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+		want []FeedItem
+	}{
+		{
+			name: "rss 2.0",
+			feed: `<?xml version="1.0"?><rss version="2.0"><channel><title>News</title>
+				<item><guid>n-2</guid><title> Listing </title><link>https://example.com/n/2</link></item>
+				<item><title>Delisting</title><link>https://example.com/n/1</link></item>
+				<item><title>Maintenance</title></item>
+			</channel></rss>`,
+			want: []FeedItem{
+				{ID: "n-2", Title: "Listing", Link: "https://example.com/n/2"},
+				// Without a guid, the link stands in for it, and without a link the title.
+				{ID: "https://example.com/n/1", Title: "Delisting", Link: "https://example.com/n/1"},
+				{ID: "Maintenance", Title: "Maintenance"},
+			},
+		},
+		{
+			name: "rss 1.0",
+			feed: `<?xml version="1.0"?><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+				<channel rdf:about="https://example.com/"><title>News</title><link>https://example.com/</link></channel>
+				<item rdf:about="https://example.com/n/1"><title>Listing</title><link>https://example.com/n/1</link></item>
+			</rdf:RDF>`,
+			want: []FeedItem{{ID: "https://example.com/n/1", Title: "Listing", Link: "https://example.com/n/1"}},
+		},
+		{
+			name: "atom",
+			feed: `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>News</title>
+				<entry><id>urn:n:2</id><title>Listing</title><link rel="edit" href="https://example.com/edit/2"/><link href="https://example.com/n/2"/></entry>
+			</feed>`,
+			want: []FeedItem{{ID: "urn:n:2", Title: "Listing", Link: "https://example.com/n/2"}},
+		},
+		{
+			name: "json feed",
+			feed: `{"version": "https://jsonfeed.org/version/1.1", "title": "News", "items": [
+				{"id": "2", "title": "Listing", "url": "https://example.com/n/2"},
+				{"id": "1", "url": "https://example.com/n/1"}
+			]}`,
+			want: []FeedItem{{ID: "2", Title: "Listing", Link: "https://example.com/n/2"}, {ID: "1", Link: "https://example.com/n/1"}},
+		},
+		{
+			name: "empty",
+			feed: `<?xml version="1.0"?><rss version="2.0"><channel><title>News</title></channel></rss>`,
+			want: []FeedItem{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := parseFeed([]byte(tt.feed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if items == nil {
+				t.Fatal("items = nil, want them never nil, for the target to be told apart from one that isn't a feed")
+			}
+			if fmt.Sprint(items) != fmt.Sprint(tt.want) {
+				t.Errorf("items = %+v, want %+v", items, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedNotAFeed(t *testing.T) {
+	if items, err := parseFeed([]byte("<html><body><h1>News</h1></body></html>")); err == nil {
+		t.Errorf("parseFeed of a page = %+v, want an error", items)
+	}
+}
+
+func TestNewFeedItems(t *testing.T) {
+	items := []FeedItem{{ID: "3", Title: "c"}, {ID: "2", Title: "b"}, {ID: "1", Title: "a"}}
+	tests := []struct {
+		name string
+		seen []string
+		want []FeedItem
+	}{
+		{"some new", []string{"1", "2"}, []FeedItem{{ID: "3", Title: "c"}}},
+		{"none new", []string{"1", "2", "3"}, nil},
+		{"all new", nil, items},
+		// Items that dropped off the feed since don't matter.
+		{"old ones gone", []string{"0", "1"}, []FeedItem{{ID: "3", Title: "c"}, {ID: "2", Title: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newFeedItems(tt.seen, items); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("newFeedItems = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
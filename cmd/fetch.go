@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+// DocCache makes sure that every page is only fetched and parsed once per run, however many of its selectors are tracked.
+type DocCache struct {
+	// How many more times to try a page whose host failed to resolve.
+	DNSRetries int
+	Hosts      *HostLimiter
+	GitHub     *GitHubRateLimit
+
+	mu    sync.Mutex
+	pages map[docKey]*cachedPage
+	heads map[docKey]*cachedHead
+	// Files linked by followAsset targets.
+	assets map[docKey]*cachedAsset
+}
+
+type docKey struct {
+	url  string
+	opts FetchOptions
+}
+
+// Page is a fetched and parsed page, along with the headers it was served with.
+type Page struct {
+	// Where the page ended up being fetched from, after redirects. Relative links are relative to it.
+	URL string
+	// nil when the page was served as json or yaml.
+	Doc *goquery.Document
+	// When the page was served as json or yaml, the document canonicalized by canonicalJSON.
+	JSON   string
+	Header http.Header
+	// Whether the server answered a conditional request with a 304, in which case there's neither a Doc nor JSON.
+	NotModified bool
+}
+
+type cachedPage struct {
+	once sync.Once
+	page *Page
+	err  error
+}
+
+type cachedHead struct {
+	once        sync.Once
+	fingerprint string
+	err         error
+}
+
+func NewDocCache(dnsRetries, perHostConcurrency int) *DocCache {
+	return &DocCache{
+		DNSRetries: dnsRetries,
+		Hosts:      NewHostLimiter(perHostConcurrency),
+		GitHub:     &GitHubRateLimit{},
+		pages:      make(map[docKey]*cachedPage),
+		heads:      make(map[docKey]*cachedHead),
+		assets:     make(map[docKey]*cachedAsset),
+	}
+}
+
+// Head returns the headFingerprint of the page from a HEAD request, at most once per run, same as Get.
+func (c *DocCache) Head(ctx context.Context, url string, opts FetchOptions) (string, error) {
+	cacheKey := docKey{url: url, opts: opts}
+	c.mu.Lock()
+	cached, ok := c.heads[cacheKey]
+	if !ok {
+		cached = &cachedHead{}
+		c.heads[cacheKey] = cached
+	}
+	c.mu.Unlock()
+
+	cached.once.Do(func() {
+		release, err := c.Hosts.Acquire(ctx, url)
+		if err != nil {
+			cached.err = err
+			return
+		}
+		defer release()
+		resp, err := request(ctx, http.MethodHead, url, opts)
+		if err != nil {
+			cached.err = err
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			cached.fingerprint = headFingerprint(resp.Header)
+		}
+	})
+	return cached.fingerprint, cached.err
+}
+
+// headFingerprint sums up the headers that tell whether the page changed without downloading it. It's empty when the server sends neither an ETag nor a Last-Modified,
+// as the length alone doesn't say much.
+func headFingerprint(header http.Header) string {
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return ""
+	}
+	return fmt.Sprintf("etag=%s; last-modified=%s; length=%s", etag, lastModified, header.Get("Content-Length"))
+}
+
+// Get returns the parsed page, fetching it if nobody has yet. Concurrent callers for the same page wait for the one fetch.
+func (c *DocCache) Get(ctx context.Context, url string, opts FetchOptions) (*Page, error) {
+	// Same url fetched another way may well serve something else.
+	cacheKey := docKey{url: url, opts: opts}
+	c.mu.Lock()
+	cached, ok := c.pages[cacheKey]
+	if !ok {
+		cached = &cachedPage{}
+		c.pages[cacheKey] = cached
+	}
+	c.mu.Unlock()
+
+	cached.once.Do(func() {
+		release, err := c.Hosts.Acquire(ctx, url)
+		if err != nil {
+			cached.err = err
+			return
+		}
+		defer release()
+		cached.page, cached.err = fetchWithDNSRetries(ctx, url, opts, c.DNSRetries)
+	})
+	return cached.page, cached.err
+}
+
+// HostLimiter caps the number of requests in flight to any single host, so that many targets on the same docs site don't get us rate-limited.
+type HostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// NewHostLimiter returns a limiter of limit requests per host, or nil, which doesn't limit anything, if limit isn't positive.
+func NewHostLimiter(limit int) *HostLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &HostLimiter{limit: limit, hosts: make(map[string]chan struct{})}
+}
+
+// Acquire waits for a free slot on the host of rawURL. The returned func gives it back.
+func (l *HostLimiter) Acquire(ctx context.Context, rawURL string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := parsed.Hostname()
+	l.mu.Lock()
+	sem, ok := l.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, l.limit)
+		l.hosts[host] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ErrTLS is what fetches fail with when the TLS handshake does, wrapping the specific reason.
+var ErrTLS = errors.New("TLS handshake failed")
+
+// classifyTLS names the reason of a TLS failure, or returns err as is if it isn't one.
+func classifyTLS(err error) error {
+	var (
+		invalidErr   x509.CertificateInvalidError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+	)
+	var reason string
+	switch {
+	case errors.As(err, &invalidErr):
+		switch invalidErr.Reason {
+		case x509.Expired:
+			reason = "certificate expired or not yet valid"
+		default:
+			reason = "invalid certificate"
+		}
+	case errors.As(err, &authorityErr):
+		reason = "certificate signed by an unknown authority"
+	case errors.As(err, &hostnameErr):
+		reason = "certificate isn't valid for " + hostnameErr.Host
+	case errors.As(err, &recordErr):
+		reason = "server doesn't speak TLS on this port"
+	case errors.As(err, &alertErr):
+		reason = "server rejected the handshake, likely no TLS version or cipher in common"
+	default:
+		return err
+	}
+	return fmt.Errorf("%w, %s: %w", ErrTLS, reason, err)
+}
+
+// ErrHostUnresolvable is what fetches fail with once the host keeps not resolving, which usually means it's gone rather than having a bad moment.
+var ErrHostUnresolvable = errors.New("host can't be resolved, likely dead")
+
+// fetchWithDNSRetries retries the fetch when it fails on resolving the host, waiting a little longer each time.
+func fetchWithDNSRetries(ctx context.Context, url string, opts FetchOptions, retries int) (*Page, error) {
+	for attempt := 0; ; attempt++ {
+		page, err := fetchPage(ctx, url, opts)
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			return page, err
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("failed to fetch content from %s: %w, DNS lookup failed %d times: %v", url, ErrHostUnresolvable, attempt+1, dnsErr)
+		}
+		fmt.Fprintf(os.Stderr, "DNS lookup of %s failed (%v), retrying...\n", dnsErr.Name, dnsErr.Err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * time.Second):
+		}
+	}
+}
+
+// get requests the page, and returns the response for the caller to close.
+func get(ctx context.Context, url string, opts FetchOptions) (*http.Response, error) {
+	return request(ctx, http.MethodGet, url, opts)
+}
+
+// What a browser asks for, for targets without an `accept` of their own.
+const defaultAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func request(ctx context.Context, method, url string, opts FetchOptions) (*http.Response, error) {
+	fetchURL := url
+	if !opts.NoCacheBuster {
+		var err error
+		fetchURL, err = withCacheBuster(url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	accept := opts.Accept
+	if accept == "" {
+		accept = defaultAccept
+	}
+	if opts.ReadStallTimeout <= 0 {
+		req, err := http.NewRequestWithContext(ctx, method, fetchURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		setRequestHeaders(req, accept, opts)
+		return client.Do(req)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stall := &stallReader{timeout: opts.ReadStallTimeout, cancel: cancel}
+	stall.timer = time.AfterFunc(stall.timeout, stall.fire)
+	req, err := http.NewRequestWithContext(ctx, method, fetchURL, nil)
+	if err != nil {
+		stall.timer.Stop()
+		cancel()
+		return nil, err
+	}
+	setRequestHeaders(req, accept, opts)
+	resp, err := client.Do(req)
+	if err != nil {
+		stall.timer.Stop()
+		cancel()
+		if stall.stalled.Load() {
+			return nil, stall.err()
+		}
+		return nil, err
+	}
+	stall.body = resp.Body
+	resp.Body = stall
+	stall.timer.Reset(stall.timeout)
+	return resp, nil
+}
+
+func setRequestHeaders(req *http.Request, accept string, opts FetchOptions) {
+	req.Header.Set("Accept", accept)
+	if opts.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", opts.IfNoneMatch)
+	}
+	if opts.IfModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", opts.IfModifiedSince)
+	}
+	if opts.Range != "" {
+		req.Header.Set("Range", opts.Range)
+	}
+}
+
+// ErrReadStall is what fetches fail with when the server stopped sending anything for longer than the --read-stall-timeout.
+var ErrReadStall = errors.New("read stalled")
+
+// stallReader wraps the body of a response, cancelling the request once no bytes came in for the timeout.
+// The timer is reset on every read that gets some, so a body that keeps trickling in never stalls, however long it takes overall; --run-timeout is for that.
+type stallReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	stalled atomic.Bool
+}
+
+func (r *stallReader) fire() {
+	r.stalled.Store(true)
+	r.cancel()
+}
+
+func (r *stallReader) err() error {
+	return fmt.Errorf("%w, nothing received for %s", ErrReadStall, r.timeout)
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if r.stalled.Load() {
+		return n, r.err()
+	}
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func (r *stallReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.body.Close()
+}
+
+// Most <meta http-equiv="refresh"> redirects followed in a row before giving up.
+const maxMetaRefreshes = 5
+
+// fetchPage fetches and parses the page, following its meta refreshes with opts.FollowMetaRefresh, the way a browser would.
+func fetchPage(ctx context.Context, url string, opts FetchOptions) (*Page, error) {
+	page, err := fetchSinglePage(ctx, url, opts)
+	visited := map[string]bool{}
+	for refreshes := 0; err == nil && opts.FollowMetaRefresh && page.Doc != nil; refreshes++ {
+		next := metaRefreshURL(page)
+		if next == "" {
+			break
+		}
+		visited[page.URL] = true
+		if visited[next] {
+			return nil, fmt.Errorf("failed to fetch content from %s: meta refresh loop detected at %s", url, next)
+		}
+		if refreshes >= maxMetaRefreshes {
+			return nil, fmt.Errorf("failed to fetch content from %s: stopped after %d meta refreshes", url, maxMetaRefreshes)
+		}
+		fmt.Fprintf(os.Stderr, "%s redirects to %s with a meta refresh, following it\n", page.URL, next)
+		page, err = fetchSinglePage(ctx, next, opts)
+	}
+	return page, err
+}
+
+// metaRefreshURL returns the absolute url the page redirects to with a <meta http-equiv="refresh" content="0; url=...">, or nothing if it doesn't.
+// Refreshes without a url only reload the page, so they don't count.
+func metaRefreshURL(page *Page) string {
+	var target string
+	page.Doc.Find("meta[http-equiv]").EachWithBreak(func(_ int, meta *goquery.Selection) bool {
+		if !strings.EqualFold(strings.TrimSpace(meta.AttrOr("http-equiv", "")), "refresh") {
+			return true
+		}
+		// The delay comes first, then the url, separated by ';' or ','.
+		content := meta.AttrOr("content", "")
+		i := strings.IndexAny(content, ";,")
+		if i < 0 {
+			return false
+		}
+		rest := strings.TrimSpace(content[i+1:])
+		if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+			if after, ok := strings.CutPrefix(strings.TrimSpace(rest[3:]), "="); ok {
+				rest = strings.TrimSpace(after)
+			}
+		}
+		rest = strings.Trim(rest, `'"`)
+		if rest == "" {
+			return false
+		}
+		base, err := url.Parse(page.URL)
+		if err != nil {
+			return false
+		}
+		ref, err := url.Parse(rest)
+		if err != nil {
+			return false
+		}
+		target = base.ResolveReference(ref).String()
+		return false
+	})
+	if target == page.URL {
+		return ""
+	}
+	return target
+}
+
+func fetchSinglePage(ctx context.Context, url string, opts FetchOptions) (*Page, error) {
+	resp, err := get(ctx, url, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch content from %s: %w", url, classifyTLS(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && (opts.IfNoneMatch != "" || opts.IfModifiedSince != "") {
+		return &Page{URL: withoutCacheBuster(resp.Request.URL), Header: resp.Header, NotModified: true}, nil
+	}
+	if !opts.AcceptStatus.Accepts(resp.StatusCode) && !(opts.Range != "" && resp.StatusCode == http.StatusPartialContent) {
+		return nil, fmt.Errorf("failed to fetch content from %s: %s", url, resp.Status)
+	}
+	body, err := rangeBody(resp, opts.Range)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch content from %s: %w", url, err)
+	}
+	if opts.JSON || isJSON(resp.Header) {
+		body, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", url, err)
+		}
+		canonical, err := canonicalJSON(body)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the json from %s: %w", url, err)
+		}
+		return &Page{URL: withoutCacheBuster(resp.Request.URL), JSON: canonical, Header: resp.Header}, nil
+	}
+	if opts.YAML || isYAML(resp.Header) {
+		body, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", url, err)
+		}
+		canonical, err := canonicalYAML(body)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the yaml from %s: %w", url, err)
+		}
+		return &Page{URL: withoutCacheBuster(resp.Request.URL), JSON: canonical, Header: resp.Header}, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the HTML from %s: %w", url, err)
+	}
+	if marker := challengeMarker(doc, resp.Header); marker != "" {
+		return nil, fmt.Errorf("failed to fetch content from %s: %w (%s)", url, ErrChallenge, marker)
+	}
+	return &Page{URL: withoutCacheBuster(resp.Request.URL), Doc: doc, Header: resp.Header}, nil
+}
+
+// byteRange is a parsed "bytes=first-last" Range. Last is -1 for one that goes on to the end, as in "bytes=1000-".
+type byteRange struct {
+	First, Last int64
+}
+
+var byteRangePattern = regexp.MustCompile(`^bytes=(\d+)-(\d*)$`)
+
+// parseByteRange parses a Range of a single span of bytes from a given offset. Several spans, or the last bytes alone as in "bytes=-500",
+// aren't supported: the content would have to be put together or depend on the size of the page.
+func parseByteRange(value string) (byteRange, error) {
+	match := byteRangePattern.FindStringSubmatch(value)
+	if match == nil {
+		return byteRange{}, fmt.Errorf("expected bytes=first-last or bytes=first-, ex. bytes=0-100000, got %q", value)
+	}
+	r := byteRange{Last: -1}
+	r.First, _ = strconv.ParseInt(match[1], 10, 64)
+	if match[2] != "" {
+		r.Last, _ = strconv.ParseInt(match[2], 10, 64)
+		if r.Last < r.First {
+			return byteRange{}, fmt.Errorf("last byte %d is before the first %d in %q", r.Last, r.First, value)
+		}
+	}
+	return r, nil
+}
+
+// rangeBody is the part of the body the Range asked for. Servers that don't support ranges ignore the header and send the whole page,
+// which is then cut down to the same bytes after saying so, so that the content is the same either way and the rest isn't downloaded.
+func rangeBody(resp *http.Response, value string) (io.Reader, error) {
+	if value == "" || resp.StatusCode == http.StatusPartialContent {
+		return resp.Body, nil
+	}
+	// Validated with the target.
+	r, _ := parseByteRange(value)
+	fmt.Fprintf(os.Stderr, "%s ignored Range %s, answering %s; reading only those bytes of the whole page\n", resp.Request.URL.Host, value, resp.Status)
+	if _, err := io.CopyN(io.Discard, resp.Body, r.First); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if r.Last < 0 {
+		return resp.Body, nil
+	}
+	return io.LimitReader(resp.Body, r.Last-r.First+1), nil
+}
+
+// isJSON says whether the response is served as json, ex. application/json or application/problem+json.
+func isJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// isYAML says whether the response is served as yaml, ex. application/yaml or text/x-yaml.
+func isYAML(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+yaml")
+}
+
+// canonicalYAML converts the yaml document to json, canonicalized as canonicalJSON does, so that it's selected from and diffed the same way.
+// Keys that aren't strings, ex. the status codes of an OpenAPI spec's responses, are written as strings, the way json has them.
+func canonicalYAML(data []byte) (string, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	return encodeJSON(withStringKeys(v))
+}
+
+// withStringKeys is the decoded yaml with the keys of every mapping as strings.
+func withStringKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			v[key] = withStringKeys(member)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, member := range v {
+			m[fmt.Sprint(key)] = withStringKeys(member)
+		}
+		return m
+	case []any:
+		for i, element := range v {
+			v[i] = withStringKeys(element)
+		}
+	}
+	return value
+}
+
+// canonicalJSON re-encodes the document with its keys sorted and a consistent indentation, so that reformatting it or reordering its keys
+// doesn't count as a change. Numbers are kept as written.
+func canonicalJSON(data []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return "", err
+	}
+	return encodeJSON(v)
+}
+
+// ErrChallenge is what fetches fail with when the page turns out to be a bot check, served with a 200 in place of the real one.
+var ErrChallenge = errors.New("served a bot challenge instead of the page")
+
+// Titles of the interstitials Cloudflare and the like serve, in lowercase.
+var challengeTitles = []string{"just a moment...", "attention required! | cloudflare", "please wait while we check your browser", "ddos-guard"}
+
+// challengeMarker returns what gives the page away as a bot challenge, or nothing if it looks like a real page.
+func challengeMarker(doc *goquery.Document, header http.Header) string {
+	if header.Get("Cf-Mitigated") == "challenge" {
+		return "cf-mitigated: challenge header"
+	}
+	title := strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+	for _, challengeTitle := range challengeTitles {
+		if title == challengeTitle {
+			return fmt.Sprintf("page titled %q", doc.Find("title").First().Text())
+		}
+	}
+	for _, selector := range []string{"#cf-browser-verification", "#challenge-form", "#cf-challenge-running", "div.cf-turnstile"} {
+		if doc.Find(selector).Length() > 0 {
+			return selector + " on the page"
+		}
+	}
+	return ""
+}
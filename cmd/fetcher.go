@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Fetcher gets the content of a target that is hashed. Targets pick theirs by name with their `fetcher` field, see fetchers.
+type Fetcher interface {
+	Fetch(ctx context.Context, target Target) (string, FetchMeta, error)
+}
+
+// Target is a single tracked url and selector, along with its options.
+type Target struct {
+	Key      string
+	URL      string
+	Selector string
+	Entry    *Entry
+	// ETag of the content from the last check, for fetchers that make conditional requests. Empty to fetch regardless.
+	ETag string
+	// Last-Modified of the page from the last check, for --conditional.
+	LastModified string
+}
+
+func newTarget(key string, entry *Entry) (Target, error) {
+	url, htmlClass, err := splitKey(key)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{Key: key, URL: url, Selector: htmlClass, Entry: entry}, nil
+}
+
+// FetchMeta is what a fetch found out besides the content itself.
+type FetchMeta struct {
+	// When the selector is a group, hash of each of its selectors. nil when the fetcher can't tell them apart.
+	Selectors map[string]string
+	// When the page is an OpenAPI or Swagger spec, hash of each of its operations, see specOperations.
+	Operations map[string]string
+	// When the css selector matched nothing on the page, the page, to look for where its content went.
+	Unmatched *goquery.Document
+	// Number of elements the selector matched on the page. nil when the content doesn't go by a selector.
+	Matches *int
+	// Url the page declares as its canonical one, when that's not the target's.
+	Canonical string
+	// Items of a feed, so that only the new ones are notified of. nil for anything else than a feed.
+	Items []FeedItem
+	// ETag the content was served with, for fetchers that make conditional requests.
+	ETag string
+	// Last-Modified the page was served with, with --conditional.
+	LastModified string
+	// Whether the server said the content is the same as of the target's ETag, in which case there's no content.
+	NotModified bool
+	// For screenshots, the png and its perceptualHash. nil for anything else.
+	Screenshot []byte
+	VisualHash uint64
+}
+
+// fetchers are the ways to get a target's content, by the name a target's `fetcher` refers to them with.
+var fetchers = map[string]func(args RunArgs) Fetcher{
+	"html":       func(args RunArgs) Fetcher { return HTMLFetcher{Args: args} },
+	"feed":       func(args RunArgs) Fetcher { return FeedFetcher{Args: args} },
+	"github":     func(args RunArgs) Fetcher { return GitHubFetcher{Args: args} },
+	"screenshot": func(args RunArgs) Fetcher { return ScreenshotFetcher{Args: args} },
+}
+
+const defaultFetcher = "html"
+
+// fetcherFor is the target's fetcher. Files on github.com get theirs from the GitHub API unless told otherwise, as their pages are rendered by scripts.
+func (args RunArgs) fetcherFor(target Target) Fetcher {
+	name := target.Entry.Fetcher
+	if name == "" {
+		name = defaultFetcher
+		if _, ok := githubContentsURL(target.URL); ok {
+			name = "github"
+		}
+	}
+	return fetchers[name](args)
+}
+
+// HTMLFetcher gets the page over http, and extracts the text of what the selector matches in it.
+type HTMLFetcher struct {
+	Args RunArgs
+}
+
+func (f HTMLFetcher) Fetch(ctx context.Context, target Target) (string, FetchMeta, error) {
+	args, entry, url, htmlClass := f.Args, target.Entry, target.URL, target.Selector
+	var meta FetchMeta
+	opts := args.fetchOptions(url, entry)
+	if args.conditionalFor(target) {
+		// Conditional on the validator the target's server offers, see chooseValidator.
+		switch entry.Validator {
+		case validatorETag:
+			opts.IfNoneMatch = target.ETag
+		case validatorLastModified:
+			opts.IfModifiedSince = target.LastModified
+		}
+	}
+	page, err := args.Docs.Get(ctx, url, opts)
+	if err != nil {
+		return "", meta, err
+	}
+	if page.NotModified {
+		meta.NotModified = true
+		return "", meta, nil
+	}
+	if args.conditionalFor(target) {
+		meta.ETag, meta.LastModified = page.Header.Get("ETag"), page.Header.Get("Last-Modified")
+	}
+	if page.Doc == nil {
+		// Served as json or yaml, which have no elements to select: the content is the whole document, or what a path selector picks in it.
+		if entry.FollowAsset {
+			return "", meta, fmt.Errorf("%s is served as json or yaml, which has no links to follow", page.URL)
+		}
+		var contentBlock string
+		meta.Operations = specOperations(page.JSON)
+		switch {
+		case entry.HeadersOnly:
+		case isJSONPath(htmlClass):
+			contentBlock, err = selectJSON(page.JSON, htmlClass)
+			if err != nil {
+				return "", meta, fmt.Errorf("failed to extract content from %s: %w", url, err)
+			}
+		default:
+			contentBlock = page.JSON
+		}
+		return withWatchedHeaders(contentBlock, page.Header, entry.WatchHeaders), meta, nil
+	}
+	meta.Canonical = canonicalURL(page.Doc, page.URL, url)
+	if entry.InnerSelector != "" {
+		// The selector is of the iframe, the content is what innerSelector matches within the page it embeds.
+		page, err = followIframe(ctx, page, htmlClass, entry, args)
+		if err != nil {
+			return "", meta, err
+		}
+		url, htmlClass = page.URL, entry.InnerSelector
+	}
+	if entry.FollowAsset {
+		contentBlock, err := followAsset(ctx, page, htmlClass, entry, args)
+		if err != nil {
+			return "", meta, err
+		}
+		return withWatchedHeaders(contentBlock, page.Header, entry.WatchHeaders), meta, nil
+	}
+	var contentBlock string
+	extractor := args.extractorFor(entry)
+	switch {
+	case entry.HeadersOnly:
+	case extractor == readabilityExtractor:
+		contentBlock, err = readableContent(page.Doc, page.URL, args.RawText)
+		if err != nil {
+			return "", meta, fmt.Errorf("failed to extract content from %s: %w", url, err)
+		}
+	case extractor != "":
+		// What the extractor prints is the content, with no further processing.
+		contentBlock, err = runExtractor(ctx, extractor, args.ExtractorTimeout, page.Doc, url, htmlClass)
+		if err != nil {
+			return "", meta, err
+		}
+	default:
+		extraction := args.extraction(entry)
+		contentBlock, err = extractContent(page.Doc, htmlClass, extraction)
+		if err != nil {
+			return "", meta, fmt.Errorf("failed to extract content from %s: %w", url, err)
+		}
+		// Counted without the index, so that verify-selectors still notices when the elements it picks from come and go.
+		selector, _ := splitMatchIndex(htmlClass, extraction.SelectorType)
+		if contentBlock == "" && extraction.SelectorType != "xpath" && !extraction.anchored() && page.Doc.Find(selector).Length() == 0 {
+			meta.Unmatched = page.Doc
+		}
+		if nodes, err := selectNodes(page.Doc, selector, extraction.SelectorType); err == nil && !extraction.anchored() {
+			matches := len(nodes)
+			meta.Matches = &matches
+		}
+		if entry.NextSelector != "" {
+			// Per-selector hashes of just the first page would point at the wrong parts.
+			contentBlock, err = followPages(ctx, page, contentBlock, entry, htmlClass, args)
+			if err != nil {
+				return "", meta, err
+			}
+		} else if extraction.SelectorType != "xpath" && !extraction.anchored() {
+			meta.Selectors = hashSelectors(page.Doc, htmlClass, args.RawText)
+		}
+	}
+	return withWatchedHeaders(contentBlock, page.Header, entry.WatchHeaders), meta, nil
+}
+
+// withWatchedHeaders appends the headersText of the watched headers to the content, if any.
+func withWatchedHeaders(contentBlock string, header http.Header, names []string) string {
+	if len(names) == 0 {
+		return contentBlock
+	}
+	headers := headersText(header, names)
+	if contentBlock == "" {
+		return headers
+	}
+	return contentBlock + "\n\n" + headers
+}
+
+// followIframe fetches the page embedded by the first iframe that htmlClass matches on parent. Relative srcs are resolved against the parent's url.
+func followIframe(ctx context.Context, parent *Page, htmlClass string, entry *Entry, args RunArgs) (*Page, error) {
+	// matchIndex is of the content, within the embedded page; only an index qualifier picks among the iframes.
+	nodes, err := selectMatches(parent.Doc, htmlClass, Extraction{SelectorType: args.extraction(entry).SelectorType})
+	if err != nil {
+		return nil, err
+	}
+	var src string
+	for _, node := range nodes {
+		if node.Data != "iframe" {
+			continue
+		}
+		if src = strings.TrimSpace(goquery.NewDocumentFromNode(node).AttrOr("src", "")); src != "" {
+			break
+		}
+	}
+	if src == "" {
+		return nil, fmt.Errorf("%s matched no iframe with a src on %s", htmlClass, parent.URL)
+	}
+	base, err := url.Parse(parent.URL)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := base.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid iframe src %q on %s: %w", src, parent.URL, err)
+	}
+	frame.Fragment = ""
+	page, err := args.Docs.Get(ctx, frame.String(), args.fetchOptions(frame.String(), entry))
+	if err != nil {
+		return nil, fmt.Errorf("iframe: %w", err)
+	}
+	if page.Doc == nil {
+		return nil, fmt.Errorf("iframe: %s is served as json or yaml, which innerSelector can't select from", page.URL)
+	}
+	return page, nil
+}
+
+const defaultMaxPages = 10
+
+// followPages follows the entry's nextSelector links from first, whose content is already extracted, and returns the content of all the pages, one after another.
+// It stops on the first page without a next link, on coming back to a page it has already been through, or at maxPages.
+func followPages(ctx context.Context, first *Page, firstContent string, entry *Entry, htmlClass string, args RunArgs) (string, error) {
+	maxPages := entry.MaxPages
+	if maxPages == 0 {
+		maxPages = defaultMaxPages
+	}
+	extraction := args.extraction(entry)
+	contents := []string{firstContent}
+	visited := map[string]bool{first.URL: true}
+	current := first
+	for len(contents) < maxPages {
+		href, ok := current.Doc.Find(entry.NextSelector).First().Attr("href")
+		if !ok || strings.TrimSpace(href) == "" {
+			break
+		}
+		base, err := url.Parse(current.URL)
+		if err != nil {
+			return "", err
+		}
+		next, err := base.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return "", fmt.Errorf("invalid next page link %q on %s: %w", href, current.URL, err)
+		}
+		next.Fragment = ""
+		if visited[next.String()] {
+			break
+		}
+		visited[next.String()] = true
+
+		current, err = args.Docs.Get(ctx, next.String(), args.fetchOptions(next.String(), entry))
+		if err != nil {
+			return "", fmt.Errorf("page %d: %w", len(contents)+1, err)
+		}
+		if current.Doc == nil {
+			return "", fmt.Errorf("page %d: %s is served as json or yaml, which htmlClass can't select from", len(contents)+1, current.URL)
+		}
+		content, err := extractContent(current.Doc, htmlClass, extraction)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract content from %s: %w", current.URL, err)
+		}
+		contents = append(contents, content)
+	}
+	return strings.Join(contents, "\n"), nil
+}
+
+// headersText is a "Name: value" line per watched header, in the order they're listed. Missing headers still get a line, so that one appearing is a change.
+func headersText(header http.Header, names []string) string {
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s: %s", http.CanonicalHeaderKey(name), strings.Join(header.Values(name), ", "))
+	}
+	return strings.Join(lines, "\n")
+}
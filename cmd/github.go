@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHubFetcher gets a file of a GitHub repository through the contents API, raw, instead of scraping the page GitHub renders it in.
+// github.com/<owner>/<repo>/blob/<ref>/<path> and raw.githubusercontent.com urls are turned into the API's; with `fetcher: "github"`, any other url
+// is taken to already be an API one, ex. of a GitHub Enterprise server. The selector isn't used.
+//
+// Requests are conditional on the ETag of the last check, and 304s don't count towards the API's rate limit.
+type GitHubFetcher struct {
+	Args RunArgs
+}
+
+var (
+	githubBlobPattern = regexp.MustCompile(`^https?://(?:www\.)?github\.com/([^/]+)/([^/]+)/blob/([^/]+)/([^?#]+)`)
+	githubRawPattern  = regexp.MustCompile(`^https?://raw\.githubusercontent\.com/([^/]+)/([^/]+)/([^/]+)/([^?#]+)`)
+)
+
+const githubAPI = "https://api.github.com"
+
+var ErrGitHubRateLimit = errors.New("GitHub API rate limit exceeded")
+
+// githubContentsURL turns the url of a file on github.com into the API url to get it from, and false for any other url.
+func githubContentsURL(url string) (string, bool) {
+	match := githubBlobPattern.FindStringSubmatch(url)
+	if match == nil {
+		match = githubRawPattern.FindStringSubmatch(url)
+	}
+	if match == nil {
+		return "", false
+	}
+	owner, repo, ref, path := match[1], match[2], match[3], match[4]
+	return fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", githubAPI, owner, repo, path, ref), true
+}
+
+func (f GitHubFetcher) Fetch(ctx context.Context, target Target) (string, FetchMeta, error) {
+	args, url := f.Args, target.URL
+	var meta FetchMeta
+	apiURL := url
+	if contentsURL, ok := githubContentsURL(url); ok {
+		apiURL = contentsURL
+	}
+	if err := args.Docs.GitHub.check(); err != nil {
+		return "", meta, fmt.Errorf("failed to fetch content from %s: %w", url, err)
+	}
+	release, err := args.Docs.Hosts.Acquire(ctx, apiURL)
+	if err != nil {
+		return "", meta, err
+	}
+	defer release()
+
+	client, err := newClient(args.fetchOptions(apiURL, target.Entry))
+	if err != nil {
+		return "", meta, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", meta, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.raw")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if args.GitHubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+args.GitHubToken)
+	}
+	if target.ETag != "" {
+		req.Header.Set("If-None-Match", target.ETag)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to fetch content from %s: %w", url, classifyTLS(err))
+	}
+	defer resp.Body.Close()
+	args.Docs.GitHub.update(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		meta.NotModified = true
+		meta.ETag = target.ETag
+		return "", meta, nil
+	default:
+		if err := args.Docs.GitHub.check(); err != nil {
+			return "", meta, fmt.Errorf("failed to fetch content from %s: %w", url, err)
+		}
+		return "", meta, fmt.Errorf("failed to fetch content from %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	meta.ETag = resp.Header.Get("ETag")
+	return string(body), meta, nil
+}
+
+// GitHubRateLimit is what's left of the GitHub API's rate limit over a run. Once it's used up, the remaining GitHub targets fail right away
+// until it resets, rather than each spending a request on being refused.
+type GitHubRateLimit struct {
+	mu      sync.Mutex
+	resetAt time.Time
+}
+
+func (l *GitHubRateLimit) check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().Before(l.resetAt) {
+		return fmt.Errorf("%w until %s; --github-token raises it", ErrGitHubRateLimit, l.resetAt.Local().Format(time.DateTime))
+	}
+	return nil
+}
+
+// update reads the rate limit headers of an API response: X-RateLimit-Remaining and X-RateLimit-Reset for the primary limit,
+// and Retry-After on the 403s and 429s of the secondary one.
+func (l *GitHubRateLimit) update(resp *http.Response) {
+	var resetAt time.Time
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			resetAt = time.Unix(reset, 0)
+		}
+	}
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil {
+			resetAt = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	if resetAt.IsZero() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if resetAt.After(l.resetAt) {
+		l.resetAt = resetAt
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+)
+
+// groupedChange is a change of a target that is part of a group, held back to be notified of along with the rest of the group.
+type groupedChange struct {
+	Key string
+	// The target's `notify` route, empty for the global notifiers.
+	Route        string
+	Notifier     Notifier
+	Notification Notification
+}
+
+// GroupNotifications collects the changes of the targets that have a `group` during a run, so that each group gets a single notification
+// listing its changed targets instead of one per target, or rather one per route its targets `notify`, so that none goes where it isn't routed to.
+// A nil *GroupNotifications holds nothing back.
+type GroupNotifications struct {
+	mu      sync.Mutex
+	changes map[string][]groupedChange
+}
+
+// Hold keeps the notification for the end of the run, and says whether it did: always false for a target without a group, or a nil collector.
+func (g *GroupNotifications) Hold(group, route, key string, notifier Notifier, notification Notification) bool {
+	if g == nil || group == "" {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.changes == nil {
+		g.changes = make(map[string][]groupedChange)
+	}
+	g.changes[group] = append(g.changes[group], groupedChange{Key: key, Route: route, Notifier: notifier, Notification: notification})
+	return true
+}
+
+// Send notifies of every group with changes, once per route of its changed targets. Each notification goes where its most severe change would have gone,
+// the first of them by key on a tie. Targets are recorded in the notified log only once their group's notification went through.
+func (g *GroupNotifications) Send(ctx context.Context, args RunArgs) {
+	if g == nil {
+		return
+	}
+	groups := make([]string, 0, len(g.changes))
+	for group := range g.changes {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		byRoute := make(map[string][]groupedChange)
+		for _, change := range g.changes[group] {
+			byRoute[change.Route] = append(byRoute[change.Route], change)
+		}
+		routes := make([]string, 0, len(byRoute))
+		for route := range byRoute {
+			routes = append(routes, route)
+		}
+		sort.Strings(routes)
+		for _, route := range routes {
+			sendGroup(ctx, args, group, byRoute[route])
+		}
+	}
+}
+
+// sendGroup sends the changes of a group that share a route as a single notification.
+func sendGroup(ctx context.Context, args RunArgs, group string, changes []groupedChange) {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	lead := changes[0]
+	messages := make([]string, len(changes))
+	for i, change := range changes {
+		messages[i] = change.Notification.Message
+		if change.Notification.Severity > lead.Notification.Severity {
+			lead = change
+		}
+	}
+	notification := Notification{
+		Message:  fmt.Sprintf("[%s] %d targets changed:\n%s", group, len(changes), strings.Join(messages, "\n")),
+		Severity: lead.Notification.Severity,
+		ID:       group,
+	}
+	if len(changes) == 1 {
+		notification = lead.Notification
+		notification.Message = fmt.Sprintf("[%s] %s", group, lead.Notification.Message)
+	}
+	notified := make(map[string]string, len(changes))
+	for _, change := range changes {
+		notified[change.Key] = change.Notification.NewHash
+	}
+	if err := deliver(ctx, lead.Notifier, notification, notified, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send notification for group %s: %v\n", group, err)
+	}
+}
+
+// GroupStatus is a row of `groups`.
+type GroupStatus struct {
+	Group string `json:"group" yaml:"group"`
+	// "changed" if any target's last check found a change, otherwise "failing" if any target's last check failed, otherwise "unchanged".
+	Status string `json:"status" yaml:"status"`
+	// Of the hashes of all the targets, so that any of them changing changes it.
+	Hash    string        `json:"hash" yaml:"hash"`
+	Members []GroupMember `json:"members" yaml:"members"`
+}
+
+type GroupMember struct {
+	ID       string `json:"id" yaml:"id"`
+	URL      string `json:"url" yaml:"url"`
+	Selector string `json:"selector" yaml:"selector"`
+	Status   string `json:"status" yaml:"status"`
+}
+
+// memberStatus is how the target's last check went.
+func memberStatus(entry *Entry) string {
+	switch {
+	case entry.ConsecutiveFailures > 0:
+		return "failing"
+	case entry.LastChecked != nil && entry.LastChanged != nil && entry.LastChanged.Equal(*entry.LastChecked):
+		return "changed"
+	default:
+		return "unchanged"
+	}
+}
+
+// showGroups prints a single status and hash per group of targets, as of their last checks, for dashboards that would rather not list every page.
+// Nothing is fetched.
+func showGroups(c *cli.Context) error {
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(hashes))
+	for key, entry := range hashes {
+		if entry.Group != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	byName := make(map[string]*GroupStatus)
+	var groups []*GroupStatus
+	combined := make(map[string][]string)
+	for _, key := range keys {
+		entry := hashes[key]
+		group, ok := byName[entry.Group]
+		if !ok {
+			group = &GroupStatus{Group: entry.Group, Status: "unchanged"}
+			byName[entry.Group] = group
+			groups = append(groups, group)
+		}
+		url, htmlClass, _ := splitKey(key)
+		member := GroupMember{ID: targetID(key, entry), URL: url, Selector: htmlClass, Status: memberStatus(entry)}
+		group.Members = append(group.Members, member)
+		switch {
+		case member.Status == "changed":
+			group.Status = "changed"
+		case member.Status == "failing" && group.Status == "unchanged":
+			group.Status = "failing"
+		}
+		combined[entry.Group] = append(combined[entry.Group], key+"\n"+entry.Hash)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+	for _, group := range groups {
+		group.Hash = getSHA256Hash(strings.Join(combined[group.Group], "\n"))
+	}
+
+	members := c.Bool("members")
+	return writeOutput(os.Stdout, c.String("format"), groups, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "GROUP\tSTATUS\tTARGETS\tHASH")
+		for _, group := range groups {
+			var changed []string
+			for _, member := range group.Members {
+				if member.Status == "changed" {
+					changed = append(changed, member.ID)
+				}
+			}
+			status := group.Status
+			if len(changed) > 0 {
+				status += ": " + strings.Join(changed, ", ")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", group.Group, status, len(group.Members), group.Hash[:12])
+			if !members {
+				continue
+			}
+			for _, member := range group.Members {
+				fmt.Fprintf(w, "  %s\t%s\t%s (%s)\t\n", member.ID, member.Status, member.URL, member.Selector)
+			}
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type recordingNotifier struct {
+	sent *[]Notification
+}
+
+func (n recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	*n.sent = append(*n.sent, notification)
+	return nil
+}
+
+func TestGroupNotificationsSendPerRoute(t *testing.T) {
+	var team, ops []Notification
+	teamNotifier, opsNotifier := recordingNotifier{&team}, recordingNotifier{&ops}
+	var groups GroupNotifications
+	groups.Hold("Binance docs", "team", "a", teamNotifier, Notification{Message: "a changed", Severity: Info})
+	groups.Hold("Binance docs", "team", "b", teamNotifier, Notification{Message: "b changed", Severity: Warn})
+	// Routed elsewhere, and more severe than the rest, which mustn't take the team's changes along with it.
+	groups.Hold("Binance docs", "ops", "c", opsNotifier, Notification{Message: "c changed", Severity: Critical})
+	groups.Send(context.Background(), RunArgs{})
+
+	if len(team) != 1 {
+		t.Fatalf("team got %d notifications, want 1", len(team))
+	}
+	if msg := team[0].Message; !strings.HasPrefix(msg, "[Binance docs] 2 targets changed:") || !strings.Contains(msg, "a changed") || !strings.Contains(msg, "b changed") || strings.Contains(msg, "c changed") {
+		t.Errorf("team got %q, want a and b only", msg)
+	}
+	if team[0].Severity != Warn {
+		t.Errorf("team's severity = %v, want that of its most severe change", team[0].Severity)
+	}
+	if len(ops) != 1 || ops[0].Message != "[Binance docs] c changed" {
+		t.Errorf("ops got %+v, want only c", ops)
+	}
+}
+
+func TestGroupNotificationsHoldWithoutGroup(t *testing.T) {
+	var groups *GroupNotifications
+	if groups.Hold("Binance docs", "", "a", nil, Notification{}) {
+		t.Error("a nil collector held a notification")
+	}
+	if (&GroupNotifications{}).Hold("", "", "a", nil, Notification{}) {
+		t.Error("a target without a group was held")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// SizePoint is the state of a target's content as of one check, kept in its history with --history-length.
+type SizePoint struct {
+	Time time.Time `json:"time" yaml:"time"`
+	// In characters.
+	Size int    `json:"size" yaml:"size"`
+	Hash string `json:"hash" yaml:"hash"`
+}
+
+// recordSize adds the point to the entry's history, dropping the oldest ones past length.
+func (e *Entry) recordSize(point SizePoint, length int) {
+	e.History = append(e.History, point)
+	if len(e.History) > length {
+		e.History = append([]SizePoint(nil), e.History[len(e.History)-length:]...)
+	}
+}
+
+// TargetHistory is a target in `history`.
+type TargetHistory struct {
+	ID       string         `json:"id" yaml:"id"`
+	URL      string         `json:"url" yaml:"url"`
+	Selector string         `json:"selector" yaml:"selector"`
+	Points   []HistoryPoint `json:"points" yaml:"points"`
+}
+
+type HistoryPoint struct {
+	SizePoint `yaml:",inline"`
+	// "first", "same", "changed", or "reverted" when the content went back to that of an earlier point, the mark of a page flapping between versions.
+	Change string `json:"change" yaml:"change"`
+}
+
+// showHistory prints the recent sizes and hashes of targets, as kept by checks with --history-length. Nothing is fetched.
+func showHistory(c *cli.Context) error {
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	url := c.String("url")
+	var keys []string
+	if url != "" {
+		keys = matchTargets(hashes, url, c.String("selector"))
+		if len(keys) == 0 {
+			return fmt.Errorf("no targets track %s", url)
+		}
+	} else {
+		for key, entry := range hashes {
+			if len(entry.History) > 0 {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	histories := make([]TargetHistory, 0, len(keys))
+	for _, key := range keys {
+		entry := hashes[key]
+		keyURL, htmlClass, _ := splitKey(key)
+		history := TargetHistory{ID: targetID(key, entry), URL: keyURL, Selector: htmlClass}
+		seen := make(map[string]bool)
+		for i, point := range entry.History {
+			change := "changed"
+			switch {
+			case i == 0:
+				change = "first"
+			case point.Hash == entry.History[i-1].Hash:
+				change = "same"
+			case seen[point.Hash]:
+				change = "reverted"
+			}
+			seen[point.Hash] = true
+			history.Points = append(history.Points, HistoryPoint{SizePoint: point, Change: change})
+		}
+		histories = append(histories, history)
+	}
+
+	return writeOutput(os.Stdout, c.String("format"), histories, func(w *tabwriter.Writer) {
+		for _, history := range histories {
+			fmt.Fprintf(w, "%s: %s (%s)\n", history.ID, history.URL, history.Selector)
+			if len(history.Points) == 0 {
+				fmt.Fprintln(w, "  no history, check with --history-length to keep some")
+			}
+			for i, point := range history.Points {
+				at := point.Time.Local().Format("2006-01-02 15:04:05")
+				delta := ""
+				if i > 0 {
+					delta = fmt.Sprintf("%+d", point.Size-history.Points[i-1].Size)
+				}
+				fmt.Fprintf(w, "  %s\t%s\t%d characters\t%s\t%s\n", at, point.Change, point.Size, delta, point.Hash[:min(12, len(point.Hash))])
+			}
+		}
+	})
+}
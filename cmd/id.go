@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Explicit ids end up in snapshot directory names, so they stick to what's safe there.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+const maxIDLength = 64
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// targetID is the name the target goes by in logs, notifications, reports and snapshot directories: its id option if it has one, or else slugID of its key.
+func targetID(key string, entry *Entry) string {
+	if entry != nil && entry.ID != "" {
+		return entry.ID
+	}
+	return slugID(key)
+}
+
+// slugID derives an id from the url and selector of the key, ex. "binance-com-api-docs-h1-3f2a9c".
+// The suffix is part of the hash of the key, so that targets whose slugs end up the same still get different ids.
+func slugID(key string) string {
+	url, htmlClass, _ := splitKey(key)
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+len("://"):]
+	}
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(url+" "+htmlClass), "-"), "-")
+	if len(slug) > maxIDLength-7 {
+		slug = strings.TrimRight(slug[:maxIDLength-7], "-")
+	}
+	return slug + "-" + getSHA256Hash(key)[:6]
+}
+
+// describeTarget is how targets are listed in the reports at the end of a run, ex. "binance-ratelimits: https://binance.com/api (h1)".
+func describeTarget(key string, entry *Entry) string {
+	url, htmlClass, _ := splitKey(key)
+	return fmt.Sprintf("%s: %s (%s)", targetID(key, entry), url, htmlClass)
+}
+
+// validateIDs checks that no two targets go by the same id.
+func validateIDs(hashes Hashes) []error {
+	keysByID := make(map[string]string, len(hashes))
+	var errs []error
+	for _, key := range dispatchOrder(hashes) {
+		id := targetID(key, hashes[key])
+		if other, ok := keysByID[id]; ok {
+			errs = append(errs, fmt.Errorf("targets %q and %q both have the id %q", other, key, id))
+			continue
+		}
+		keysByID[id] = key
+	}
+	return errs
+}
+
+// IDs are the ids set explicitly on targets, by key, for SnapshotStore to find their directories.
+func (h Hashes) IDs() map[string]string {
+	ids := make(map[string]string)
+	for key, entry := range h {
+		if entry.ID != "" {
+			ids[key] = entry.ID
+		}
+	}
+	return ids
+}
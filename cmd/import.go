@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+)
+
+// TargetRow is a target listed in a file to import.
+type TargetRow struct {
+	URL      string
+	Selector string
+	// Empty to leave the target's id as it is.
+	ID string
+}
+
+// readTargetsFile reads the url,selector rows of a csv, or a tsv if the path ends with .tsv.
+// With a header row, the columns are found by name instead, url, selector and optionally id, and the others are ignored; so the output of `export` imports back.
+func readTargetsFile(filePath string) ([]TargetRow, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if strings.HasSuffix(filePath, ".tsv") {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	reader.TrimLeadingSpace = true
+
+	var rows []TargetRow
+	columns := map[string]int{"url": 0, "selector": 1, "id": -1}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			header := make(map[string]int, len(record))
+			for i, name := range record {
+				header[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			if _, ok := header["url"]; ok {
+				if _, ok := header["selector"]; ok {
+					columns["url"], columns["selector"] = header["url"], header["selector"]
+					if i, ok := header["id"]; ok {
+						columns["id"] = i
+					}
+					continue
+				}
+			}
+			if len(record) != 2 {
+				return nil, fmt.Errorf("%s:1: expected url,selector rows, or a header naming the url and selector columns", filePath)
+			}
+		}
+		row := TargetRow{URL: strings.TrimSpace(record[columns["url"]]), Selector: strings.TrimSpace(record[columns["selector"]])}
+		if i := columns["id"]; i >= 0 {
+			row.ID = strings.TrimSpace(record[i])
+		}
+		if row.URL == "" || row.Selector == "" {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: both url and selector are required", filePath, line)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// seedEntry fetches the target and sets its hashes to what's currently there, without treating it as a change.
+func seedEntry(ctx context.Context, entry *Entry, key string, args RunArgs) error {
+	target, err := newTarget(key, entry)
+	if err != nil {
+		return err
+	}
+	contentBlock, meta, err := args.fetcherFor(target).Fetch(ctx, target)
+	if err != nil {
+		return err
+	}
+	entry.Hash = getSHA256Hash(contentBlock)
+	entry.Selectors = meta.Selectors
+	return nil
+}
+
+func importTargets(c *cli.Context) error {
+	targetsFile := c.String("file")
+	if targetsFile == "" {
+		return fmt.Errorf("--file is required")
+	}
+	targetsFile, err := expandHome(targetsFile)
+	if err != nil {
+		return err
+	}
+	rows, err := readTargetsFile(targetsFile)
+	if err != nil {
+		return err
+	}
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	// Importing is a way to start tracking things, so the hashes file doesn't have to exist yet.
+	hashes, err := loadHashes(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		hashes, err = make(Hashes), nil
+	}
+	if err != nil {
+		return err
+	}
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got: %d", concurrency)
+	}
+	githubToken, err := resolveSecret(c.String("github-token"))
+	if err != nil {
+		return fmt.Errorf("--github-token: %w", err)
+	}
+	args := RunArgs{
+		SelectorType:      c.String("selector-type"),
+		RawText:           c.Bool("raw-text"),
+		SOCKS5:            c.String("socks5"),
+		DNSServer:         c.String("dns-server"),
+		MaxRedirects:      c.Int("max-redirects"),
+		FollowMetaRefresh: c.Bool("follow-meta-refresh"),
+		InsecureHosts:     insecureHosts(c),
+		GitHubToken:       githubToken,
+		Docs:              NewDocCache(0, c.Int("per-host-concurrency")),
+	}
+	if args.SelectorType != "css" && args.SelectorType != "xpath" {
+		return fmt.Errorf("selector type must be 'css' or 'xpath', got: %s", args.SelectorType)
+	}
+
+	var (
+		mu      sync.Mutex
+		skipped int
+		errs    []error
+		// Seeded targets, added to hashes once they're all done, so that the loop below can keep reading hashes meanwhile.
+		seeded = make(Hashes)
+	)
+	seen := make(map[string]bool, len(rows))
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for _, row := range rows {
+		key := joinKey(row.URL, row.Selector)
+		existing, ok := hashes[key]
+		if seen[key] || (ok && !c.Bool("force")) {
+			skipped++
+			continue
+		}
+		seen[key] = true
+		// Forcing keeps the target's options, only its hashes are redone.
+		entry := &Entry{}
+		if ok {
+			copied := *existing
+			entry = &copied
+		}
+		// Derived ids, as exported, are left derived.
+		if row.ID != "" && row.ID != slugID(key) {
+			entry.ID = row.ID
+		}
+		if err := entry.validate(); err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("%s (%s): %w", row.URL, row.Selector, err))
+			mu.Unlock()
+			continue
+		}
+		g.Go(func() error {
+			err := seedEntry(context.Background(), entry, key, args)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s): %w", row.URL, row.Selector, err))
+				return nil
+			}
+			seeded[key] = entry
+			return nil
+		})
+	}
+	g.Wait()
+	for key, entry := range seeded {
+		hashes[key] = entry
+	}
+	added := len(seeded)
+
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Failed to import:\n%v\n", errors.Join(errs...))
+	}
+	if err := errors.Join(validateIDs(hashes)...); err != nil {
+		return fmt.Errorf("not saving the imported targets: %w", err)
+	}
+	if added > 0 {
+		if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Added %d targets, skipped %d already tracked, %d failed\n", added, skipped, len(errs))
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d targets failed to import", len(errs), len(rows))
+	}
+	return nil
+}
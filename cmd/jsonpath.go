@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// isJSONPath says whether the selector is a path into a json document rather than a css or xpath one, going by the `$` they start with.
+func isJSONPath(selector string) bool {
+	return strings.HasPrefix(selector, "$")
+}
+
+// selectJSON returns what the path matches in the document, as canonicalized json. After its `$.`, the path is a GJSON one,
+// see https://github.com/tidwall/gjson/blob/master/SYNTAX.md, ex. `$.paths`, `$.servers.0.url` or `$.symbols.#(status=="TRADING")#.symbol`.
+// `$` alone is the whole document. Matching nothing is an error rather than empty content, which would look like everything was removed.
+func selectJSON(document, path string) (string, error) {
+	if path == "$" {
+		return document, nil
+	}
+	gjsonPath, ok := strings.CutPrefix(path, "$.")
+	if !ok || gjsonPath == "" {
+		return "", fmt.Errorf("jsonPath: expected $ or $. followed by a path, got %q", path)
+	}
+	result := gjson.Get(document, gjsonPath)
+	if !result.Exists() {
+		return "", fmt.Errorf("%s matched nothing in the json", path)
+	}
+	return canonicalJSON([]byte(result.Raw))
+}
+
+// encodeJSON is the way canonicalJSON writes documents, for any decoded value.
+func encodeJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSelectJSON(t *testing.T) {
+	document, err := canonicalJSON([]byte(`{
+		"openapi": "3.0.0",
+		"servers": [{"url": "https://api.example.com"}, {"url": "https://testnet.example.com"}],
+		"paths": {"/v1/ticker": {"get": {}}, "/v2/order": {"post": {}}},
+		"symbols": [{"symbol": "BTCUSDT", "status": "TRADING"}, {"symbol": "LUNAUSDT", "status": "BREAK"}, {"symbol": "ETHUSDT", "status": "TRADING"}],
+		"x.rate": 1200
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"root", "$", document},
+		{"key", "$.openapi", `"3.0.0"`},
+		{"nested", "$.paths./v1/ticker", "{\n  \"get\": {}\n}"},
+		{"index", "$.servers.1.url", `"https://testnet.example.com"`},
+		{"every element", "$.servers.#.url", "[\n  \"https://api.example.com\",\n  \"https://testnet.example.com\"\n]"},
+		{"keys", "$.paths.@keys", "[\n  \"/v1/ticker\",\n  \"/v2/order\"\n]"},
+		{"filter", `$.symbols.#(status=="TRADING")#.symbol`, "[\n  \"BTCUSDT\",\n  \"ETHUSDT\"\n]"},
+		{"escaped dot", `$.x\.rate`, "1200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectJSON(document, tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("selectJSON(%s) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectJSONErrors(t *testing.T) {
+	document := `{"paths": {}, "servers": []}`
+	for _, path := range []string{
+		// Not a path.
+		"$paths", "$.",
+		// Matching nothing.
+		"$.components", "$.servers.0", `$.servers.#(url=="x")`,
+	} {
+		if got, err := selectJSON(document, path); err == nil {
+			t.Errorf("selectJSON(%s) = %s, want an error", path, got)
+		}
+	}
+}
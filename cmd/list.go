@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
+)
+
+// TargetInfo is a row of `list`.
+type TargetInfo struct {
+	ID                  string     `json:"id" yaml:"id"`
+	URL                 string     `json:"url" yaml:"url"`
+	Selector            string     `json:"selector" yaml:"selector"`
+	Hash                string     `json:"hash" yaml:"hash"`
+	Enabled             bool       `json:"enabled" yaml:"enabled"`
+	LastChecked         *time.Time `json:"lastChecked,omitempty" yaml:"lastChecked,omitempty"`
+	LastChanged         *time.Time `json:"lastChanged,omitempty" yaml:"lastChanged,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures" yaml:"consecutiveFailures"`
+}
+
+// Stats is the output of `stats`.
+type Stats struct {
+	Targets int `json:"targets" yaml:"targets"`
+	Hosts   int `json:"hosts" yaml:"hosts"`
+	// Targets that never got a hash.
+	Unhashed int `json:"unhashed" yaml:"unhashed"`
+	// Targets whose last check failed.
+	Failing     int        `json:"failing" yaml:"failing"`
+	Disabled    int        `json:"disabled" yaml:"disabled"`
+	LastChecked *time.Time `json:"lastChecked,omitempty" yaml:"lastChecked,omitempty"`
+}
+
+// writeOutput prints v in the requested format, with `table` writing the human readable version.
+func writeOutput(w io.Writer, format string, v any, table func(w *tabwriter.Writer)) error {
+	switch format {
+	case "", "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	case "json":
+		out, err := json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+func loadTargets(c *cli.Context) ([]TargetInfo, error) {
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]TargetInfo, 0, len(hashes))
+	for key, entry := range hashes {
+		url, htmlClass, err := splitKey(key)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, TargetInfo{
+			ID:                  targetID(key, entry),
+			URL:                 url,
+			Selector:            htmlClass,
+			Hash:                entry.Hash,
+			Enabled:             entry.IsEnabled(),
+			LastChecked:         entry.LastChecked,
+			LastChanged:         entry.LastChanged,
+			ConsecutiveFailures: entry.ConsecutiveFailures,
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].URL != targets[j].URL {
+			return targets[i].URL < targets[j].URL
+		}
+		return targets[i].Selector < targets[j].Selector
+	})
+	return targets, nil
+}
+
+func listTargets(c *cli.Context) error {
+	targets, err := loadTargets(c)
+	if err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, c.String("format"), targets, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tURL\tSELECTOR\tHASH\tLAST CHECKED\tFAILURES\tENABLED")
+		for _, t := range targets {
+			lastChecked := "never"
+			if t.LastChecked != nil {
+				lastChecked = t.LastChecked.Format(time.RFC3339)
+			}
+			hash := t.Hash
+			if len(hash) > 12 {
+				hash = hash[:12]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%t\n", t.ID, t.URL, t.Selector, hash, lastChecked, t.ConsecutiveFailures, t.Enabled)
+		}
+	})
+}
+
+// exportTargets writes the targets as a csv (or tsv), for reviewing them in a spreadsheet. Edits go back in through `import`, which reads the same columns.
+func exportTargets(c *cli.Context) error {
+	targets, err := loadTargets(c)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(os.Stdout)
+	switch format := c.String("format"); format {
+	case "csv":
+	case "tsv":
+		w.Comma = '\t'
+	default:
+		return fmt.Errorf("unknown export format: %s", format)
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	w.Write([]string{"id", "url", "selector", "lastHash", "lastChecked", "lastChanged", "consecutiveFailures"})
+	for _, t := range targets {
+		w.Write([]string{t.ID, t.URL, t.Selector, t.Hash, formatTime(t.LastChecked), formatTime(t.LastChanged), strconv.Itoa(t.ConsecutiveFailures)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func showStats(c *cli.Context) error {
+	targets, err := loadTargets(c)
+	if err != nil {
+		return err
+	}
+	stats := Stats{Targets: len(targets)}
+	hosts := make(map[string]bool)
+	for _, t := range targets {
+		if parsed, err := url.Parse(t.URL); err == nil {
+			hosts[parsed.Host] = true
+		}
+		if t.Hash == "" {
+			stats.Unhashed++
+		}
+		if t.ConsecutiveFailures > 0 {
+			stats.Failing++
+		}
+		if !t.Enabled {
+			stats.Disabled++
+		}
+		if t.LastChecked != nil && (stats.LastChecked == nil || t.LastChecked.After(*stats.LastChecked)) {
+			stats.LastChecked = t.LastChecked
+		}
+	}
+	stats.Hosts = len(hosts)
+	return writeOutput(os.Stdout, c.String("format"), stats, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Targets:\t%d\n", stats.Targets)
+		fmt.Fprintf(w, "Hosts:\t%d\n", stats.Hosts)
+		fmt.Fprintf(w, "Unhashed:\t%d\n", stats.Unhashed)
+		fmt.Fprintf(w, "Failing:\t%d\n", stats.Failing)
+		fmt.Fprintf(w, "Disabled:\t%d\n", stats.Disabled)
+		if stats.LastChecked != nil {
+			fmt.Fprintf(w, "Last checked:\t%s\n", stats.LastChecked.Format(time.RFC3339))
+		}
+	})
+}
@@ -1,198 +1,2011 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
-	"net/http"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
-	"github.com/PuerkitoBio/goquery"
-	"github.com/Valera6/doc_scraper/utils"
 	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Valera6/doc_scraper/pkg/checker"
 )
 
 // Instead of hashing the contents, could also just make a call with [If-Modified-Since Header](<https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/If-Modified-Since>)
 // But that wouldn't scale to some exchanges. Can still do as a backup option if needed - open an issue.
-type Hashes map[string]string
+type Hashes map[string]*Entry
+
+// Entry is the value stored under each key of the hashes file.
+// Targets without any options are written as just the hash string, so older files keep loading as before.
+type Entry struct {
+	Hash string `json:"hash"`
+
+	// Name of the target in logs, notifications, reports and snapshot directories, ex. "binance-ratelimits". Derived from the url and selector when not set.
+	ID string `json:"id,omitempty"`
+	// Url and selector of the target in stores keyed by id, see rekey, where they're attributes that can be edited rather than the key.
+	// Always empty in memory, where the key is still the url and selector.
+	URL      string `json:"url,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	// Whether the target was loaded from a store keyed by id, and so gets saved to one.
+	keyedByID bool
+	// Set to false to pause checking the target, without losing its hash and options.
+	Enabled *bool `json:"enabled,omitempty"`
+	// How to get the content, one of fetchers. Defaults to "html".
+	Fetcher string `json:"fetcher,omitempty"`
+	// Same as curl's --resolve: "host:port:addr". Connections to host:port go to addr, while the Host header and TLS SNI are left alone.
+	Resolve string `json:"resolve,omitempty"`
+	// Accept any TLS certificate of the target, ex. an expired one, instead of failing the check.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// "host:port" of a SOCKS5 proxy to fetch through, in place of --socks5.
+	SOCKS5 string `json:"socks5,omitempty"`
+	// DNS server to resolve the target's host with, ex. "1.1.1.1", in place of --dns-server.
+	DNSServer string `json:"dnsServer,omitempty"`
+	// Fetch the url as is, without the nocache parameter that otherwise gets past caches, for servers that take any unknown parameter for another page or reject it.
+	NoCacheBuster bool `json:"noCacheBuster,omitempty"`
+	// Accept header to fetch with, ex. "application/json" for endpoints that serve their docs as json too. Json responses are hashed canonicalized, see canonicalJSON.
+	Accept string `json:"accept,omitempty"`
+	// "json" or "yaml" to parse the response as such even when it isn't served as such. Json and yaml responses are hashed whole, as json,
+	// or only what htmlClass picks when it's a path like "$.paths", see selectJSON.
+	Format string `json:"format,omitempty"`
+	// Range header to fetch with, ex. "bytes=0-100000", for huge pages of which only the top matters. Only those bytes are hashed,
+	// cut out of the whole page when the server ignores the header.
+	Range string `json:"range,omitempty"`
+	// "css" (default) or "xpath"; how to read htmlClass.
+	SelectorType string `json:"selectorType,omitempty"`
+	// Hash the items of the content regardless of their order, for lists that get shuffled on every request.
+	// Items are what itemSelector (css) matches within the content, or its lines if there's no itemSelector.
+	OrderInsensitive bool   `json:"orderInsensitive,omitempty"`
+	ItemSelector     string `json:"itemSelector,omitempty"`
+	// When set, htmlClass selects an iframe, and the content is what this selects in the page the iframe embeds.
+	InnerSelector string `json:"innerSelector,omitempty"`
+	// When set, htmlClass selects a link, ex. to a Postman collection or a csv, and the content is the checksum of the file it links to, see followAsset.
+	FollowAsset bool `json:"followAsset,omitempty"`
+	// For listings split over several pages: css selector of the link to the next page, followed up to maxPages (default 10) pages.
+	// The content of all of them is hashed together.
+	NextSelector string `json:"nextSelector,omitempty"`
+	MaxPages     int    `json:"maxPages,omitempty"`
+	// Normalizations of the text before it's hashed, from "lowercase" and "nfc", for pages whose case or unicode encoding varies harmlessly.
+	Transforms []string `json:"transforms,omitempty"`
+	// Text the content starts and ends at, left out, instead of the selector: ex. everything between the "Rate Limits" and "Error Codes" headings
+	// of the page. Survives markup changes as long as the text stays. Either can be left out, for the start or end of the page.
+	StartAnchor string `json:"startAnchor,omitempty"`
+	EndAnchor   string `json:"endAnchor,omitempty"`
+	// When the selector matches several elements, only take the one at this index, from 0, ex. 1 for the second table of the page.
+	// Same as ending a css selector with the index, ex. "table.rates[1]". Applies to innerSelector when set.
+	MatchIndex *int `json:"matchIndex,omitempty"`
+	// Command to extract the content with instead of the selector, in place of --extractor; see runExtractor. "readability" takes the main content of the page instead, see readableContent.
+	Extractor string `json:"extractor,omitempty"`
+	// Only hash what this regexp captures in the content (its first group, or the whole match), ex. a version string or a fee.
+	ExtractRegex string `json:"extractRegex,omitempty"`
+	// Response headers to hash along with the content, ex. ["X-API-Version", "Link"], for changes that only show in the metadata.
+	WatchHeaders []string `json:"watchHeaders,omitempty"`
+	// Hash only the watchHeaders, ignoring the body and the selector.
+	HeadersOnly bool `json:"headersOnly,omitempty"`
+	// Name of a set of related targets, ex. "Binance docs": their changes found by a run are notified of together, and `groups` sums them up.
+	Group string `json:"group,omitempty"`
+	// Name of the notifier from the config file to send this target's changes to, instead of the global one.
+	Notify string `json:"notify,omitempty"`
+	// "info" (default), "warn" or "critical": the severity of the target's changes, for the config's severityRoutes.
+	Severity string `json:"severity,omitempty"`
+	// Targets with a higher priority are checked first, so that the important ones are done before a --run-timeout could cut the run short.
+	Priority int `json:"priority,omitempty"`
+	// Targets checked less than this long ago are skipped, so a frequent cron doesn't refetch heavy pages every time.
+	MinInterval Duration `json:"minInterval,omitempty"`
+	// Cron expression, ex. "0 9 * * *": the target is only checked by runs that come after a time it fires at that it wasn't checked since.
+	// Lets a single frequent cron check each target on its own cadence.
+	Schedule string `json:"schedule,omitempty"`
+	// For pages that are supposed to update regularly: alert if the content hasn't changed for this long.
+	ExpectChangeWithin Duration `json:"expectChangeWithin,omitempty"`
+	// Changes leaving the content at least this similar (0 to 1) to the last notified version don't count, in place of --similarity-threshold.
+	SimilarityThreshold float64 `json:"similarityThreshold,omitempty"`
+
+	// Everything below is maintained by the runs themselves.
+	LastChecked *time.Time `json:"lastChecked,omitempty"`
+	// When htmlClass is a group like "div.a, div.b", hash of each of its selectors, so that notifications can say which one changed.
+	Selectors map[string]string `json:"selectors,omitempty"`
+	// When the target is an OpenAPI or Swagger spec, hash of each of its operations, so that notifications can say which endpoints changed.
+	Operations map[string]string `json:"operations,omitempty"`
+	// Number of checks in a row that failed to fetch or parse the target. Reset on the first one that succeeds.
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	// After a failure, the target isn't checked again before this, backing off further with every failure in a row.
+	NextCheck *time.Time `json:"nextCheck,omitempty"`
+	// When the hash last changed, or when the target was first checked.
+	LastChanged *time.Time `json:"lastChanged,omitempty"`
+	// Set once the target was reported as stale, so that it's only reported again after it changes.
+	StaleNotified bool `json:"staleNotified,omitempty"`
+	// ETag of the content as of the last check, for fetchers that make conditional requests (github).
+	ETag string `json:"etag,omitempty"`
+	// Last-Modified of the page as of the last check, for --conditional.
+	LastModified string `json:"lastModified,omitempty"`
+	// What --conditional checks the target with, see chooseValidator: "etag", "last-modified" or "hash". Empty until its first check with it.
+	Validator string `json:"validator,omitempty"`
+	// ETag, Last-Modified and Content-Length of the page as of the last check, for --head-first to tell it didn't change from a HEAD request.
+	HeadFingerprint string `json:"headFingerprint,omitempty"`
+	// Size and hash of the content as of the recent checks, oldest first, with --history-length.
+	History []SizePoint `json:"history,omitempty"`
+	// Number of elements the selector matched as of the last check, for verify-selectors to notice when that changes a lot.
+	Matches *int `json:"matches,omitempty"`
+	// Number of checks in a row that took the server's word that nothing changed, from a 304 or --head-first, without downloading the content. See --verify-every.
+	UnverifiedChecks int `json:"unverifiedChecks,omitempty"`
+	// Ids of the items of a feed as of the last check, to tell the new ones apart.
+	SeenItems []string `json:"seenItems,omitempty"`
+	// simHash of the last version that was notified of, while a similarity threshold applies to the target.
+	SimHash string `json:"simHash,omitempty"`
+	// The url the page declares as canonical with <link rel="canonical">, when the target is tracked under another one.
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// Duration is a time.Duration that is written as "1h30m" in json.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expected a duration like \"1h30m\", got %s", data)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (e *Entry) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var hash string
+	if err := json.Unmarshal(data, &hash); err == nil {
+		*e = Entry{Hash: hash}
+		return nil
+	}
+	// Unknown fields are rejected, so that a typo in an option doesn't silently turn it off.
+	type plain Entry
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(e))
+}
+
+// validate checks the options that json alone can't, naming the offending field.
+func (e *Entry) validate() error {
+	var errs []error
+	if e.URL != "" || e.Selector != "" {
+		errs = append(errs, fmt.Errorf("url and selector: only for stores keyed by id, otherwise they're the key"))
+	}
+	if e.ID != "" && (!idPattern.MatchString(e.ID) || len(e.ID) > maxIDLength) {
+		errs = append(errs, fmt.Errorf("id: must be at most %d letters, digits, '.', '_' or '-', starting with a letter or digit, got %q", maxIDLength, e.ID))
+	}
+	if e.Severity != "" {
+		if _, err := parseSeverity(e.Severity); err != nil {
+			errs = append(errs, fmt.Errorf("severity: %w", err))
+		}
+	}
+	if _, ok := fetchers[e.Fetcher]; e.Fetcher != "" && !ok {
+		errs = append(errs, fmt.Errorf("fetcher: unknown fetcher %q", e.Fetcher))
+	}
+	switch e.SelectorType {
+	case "", "css", "xpath":
+	default:
+		errs = append(errs, fmt.Errorf("selectorType: must be 'css' or 'xpath', got %q", e.SelectorType))
+	}
+	if e.Format != "" && e.Format != "html" && e.Format != "json" && e.Format != "yaml" {
+		errs = append(errs, fmt.Errorf("format: must be 'html', 'json' or 'yaml', got %q", e.Format))
+	}
+	if e.Range != "" {
+		if _, err := parseByteRange(e.Range); err != nil {
+			errs = append(errs, fmt.Errorf("range: %w", err))
+		}
+		if e.Format == "json" || e.Format == "yaml" || e.HeadersOnly {
+			errs = append(errs, fmt.Errorf("range: can't be combined with format json or yaml, which need the whole document, or headersOnly, which doesn't download it"))
+		}
+	}
+	if e.Resolve != "" {
+		parts := strings.SplitN(e.Resolve, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			errs = append(errs, fmt.Errorf("resolve: expected format 'host:port:addr', got %q", e.Resolve))
+		}
+	}
+	if e.SOCKS5 != "" {
+		if _, _, err := net.SplitHostPort(e.SOCKS5); err != nil {
+			errs = append(errs, fmt.Errorf("socks5: expected format 'host:port', got %q", e.SOCKS5))
+		}
+	}
+	if e.DNSServer != "" {
+		if _, err := dnsServerAddress(e.DNSServer); err != nil {
+			errs = append(errs, fmt.Errorf("dnsServer: %w", err))
+		}
+	}
+	if e.ItemSelector != "" && !e.OrderInsensitive {
+		errs = append(errs, fmt.Errorf("itemSelector: only used along with orderInsensitive: true"))
+	}
+	for _, name := range e.Transforms {
+		if _, ok := textTransforms[name]; !ok {
+			errs = append(errs, fmt.Errorf("transforms: unknown transform %q, expected 'lowercase' or 'nfc'", name))
+		}
+	}
+	if e.ExtractRegex != "" {
+		if _, err := regexp.Compile(e.ExtractRegex); err != nil {
+			errs = append(errs, fmt.Errorf("extractRegex: %w", err))
+		}
+	}
+	if e.MaxPages < 0 {
+		errs = append(errs, fmt.Errorf("maxPages: can't be negative, got %d", e.MaxPages))
+	}
+	if e.MaxPages != 0 && e.NextSelector == "" {
+		errs = append(errs, fmt.Errorf("maxPages: only used along with nextSelector"))
+	}
+	if e.InnerSelector != "" && e.HeadersOnly {
+		errs = append(errs, fmt.Errorf("innerSelector: not used along with headersOnly, which ignores the body"))
+	}
+	if e.StartAnchor != "" || e.EndAnchor != "" {
+		switch {
+		case e.ItemSelector != "":
+			errs = append(errs, fmt.Errorf("startAnchor, endAnchor: not used along with itemSelector, as they take the page's text rather than its elements"))
+		case e.NextSelector != "":
+			errs = append(errs, fmt.Errorf("startAnchor, endAnchor: not used along with nextSelector, as they'd have to be on every page"))
+		case e.HeadersOnly:
+			errs = append(errs, fmt.Errorf("startAnchor, endAnchor: not used along with headersOnly, which ignores the body"))
+		}
+	}
+	if e.MatchIndex != nil {
+		switch {
+		case *e.MatchIndex < 0:
+			errs = append(errs, fmt.Errorf("matchIndex: can't be negative, got %d", *e.MatchIndex))
+		case e.StartAnchor != "" || e.EndAnchor != "":
+			errs = append(errs, fmt.Errorf("matchIndex: not used along with startAnchor or endAnchor, which take the page's text rather than its elements"))
+		case e.HeadersOnly:
+			errs = append(errs, fmt.Errorf("matchIndex: not used along with headersOnly, which ignores the body"))
+		}
+	}
+	if e.FollowAsset && (e.NextSelector != "" || e.HeadersOnly || e.Extractor != "" || e.StartAnchor != "" || e.EndAnchor != "" || e.OrderInsensitive || e.ExtractRegex != "") {
+		errs = append(errs, fmt.Errorf("followAsset: not used along with nextSelector, headersOnly, extractor, anchors, orderInsensitive or extractRegex, as the content is the checksum of the file"))
+	}
+	if e.Extractor != "" && e.HeadersOnly {
+		errs = append(errs, fmt.Errorf("extractor: not used along with headersOnly, which ignores the body"))
+	}
+	if e.HeadersOnly && len(e.WatchHeaders) == 0 {
+		errs = append(errs, fmt.Errorf("headersOnly: needs watchHeaders to have something to hash"))
+	}
+	if e.Schedule != "" {
+		if _, err := parseSchedule(e.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("schedule: %w", err))
+		}
+	}
+	if e.MinInterval < 0 {
+		errs = append(errs, fmt.Errorf("minInterval: can't be negative, got %s", time.Duration(e.MinInterval)))
+	}
+	if e.SimilarityThreshold < 0 || e.SimilarityThreshold > 1 {
+		errs = append(errs, fmt.Errorf("similarityThreshold: must be between 0 and 1, got %v", e.SimilarityThreshold))
+	}
+	if e.SimHash != "" {
+		if _, err := parseSimHash(e.SimHash); err != nil {
+			errs = append(errs, fmt.Errorf("simHash: expected 16 hex digits, got %q", e.SimHash))
+		}
+	}
+	if e.ExpectChangeWithin < 0 {
+		errs = append(errs, fmt.Errorf("expectChangeWithin: can't be negative, got %s", time.Duration(e.ExpectChangeWithin)))
+	}
+	return errors.Join(errs...)
+}
+
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	full, err := json.Marshal(plain(e))
+	if err != nil {
+		return nil, err
+	}
+	hashOnly, err := json.Marshal(plain{Hash: e.Hash})
+	if err != nil {
+		return nil, err
+	}
+	if string(full) == string(hashOnly) {
+		return json.Marshal(e.Hash)
+	}
+	return full, nil
+}
 
+// getSHA256Hash is the same hash the checker package compares content by, so that stores work with either.
 func getSHA256Hash(text string) string {
-	hash := sha256.Sum256([]byte(text))
-	return hex.EncodeToString(hash[:])
+	return checker.Hash(text)
 }
 
+// Files whose name ends with .gz are transparently gzipped.
 func loadHashes(filePath string) (Hashes, error) {
 	var hashes Hashes
 	file, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(file, &hashes)
-	if err != nil {
-		return nil, err
+	if strings.HasSuffix(filePath, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(file))
+		if err != nil {
+			return nil, err
+		}
+		file, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(file, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s%s: %w", filePath, jsonErrorPosition(file, err), err)
+	}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// Every target is decoded on its own, so that errors can say which one is broken.
+	hashes = make(Hashes, len(raw))
+	var errs []error
+	for _, key := range keys {
+		entry := &Entry{}
+		if err := json.Unmarshal(raw[key], entry); err != nil {
+			errs = append(errs, fmt.Errorf("target %q: %w", key, err))
+			continue
+		}
+		targetKey, err := entry.storeKey(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := entry.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("target %q: %w", key, err))
+			continue
+		}
+		if other, ok := hashes[targetKey]; ok {
+			errs = append(errs, fmt.Errorf("targets %q and %q both track %q", targetID(targetKey, other), key, targetKey))
+			continue
+		}
+		hashes[targetKey] = entry
+	}
+	errs = append(errs, validateIDs(hashes)...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid targets in %s:\n%w", filePath, errors.Join(errs...))
 	}
 	return hashes, nil
 }
 
-func saveHashes(filePath string, hashes Hashes) error {
-	file, err := json.MarshalIndent(hashes, "", "    ")
+// jsonErrorPosition turns the byte offset of a json syntax or type error into ':line:column', to point at where a hand-edited file broke.
+// It's empty for other errors.
+func jsonErrorPosition(data []byte, err error) string {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return ""
+	}
+	// The offset is past the byte the error is at, the last one read.
+	before := data[:max(min(int(offset), len(data))-1, 0)]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf(":%d:%d", line, column)
+}
+
+// compact writes minified json, which is a lot smaller and quicker for large stores.
+func saveHashes(filePath string, hashes Hashes, compact bool) error {
+	var stored any = hashes
+	if hashes.keyedByID() {
+		stored = hashes.byID()
+	}
+	var file []byte
+	var err error
+	if compact {
+		file, err = json.Marshal(stored)
+	} else {
+		file, err = json.MarshalIndent(stored, "", "    ")
+	}
 	if err != nil {
 		return err
 	}
+	if strings.HasSuffix(filePath, ".gz") {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(file); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		file = buf.Bytes()
+	}
 	return os.WriteFile(filePath, file, 0644)
 }
 
-func writeChanges(hashes Hashes, key string, init bool, tgArgs TgArgs) {
+func joinKey(url, htmlClass string) string {
+	return url + "\n\n###\n\n" + htmlClass
+}
+
+func splitKey(key string) (url, htmlClass string, err error) {
 	parts := strings.Split(key, "\n\n###\n\n")
 	if len(parts) != 2 {
-		fmt.Fprintf(os.Stderr, "Key format is incorrect, expecting 'url\\n\\n###\\n\\nhtmlClass' in hashes json file. Got: %s\n", key)
-		return
+		return "", "", fmt.Errorf("key format is incorrect, expecting 'url\\n\\n###\\n\\nhtmlClass' in hashes json file. Got: %s", key)
 	}
-	url, htmlClass := parts[0], parts[1]
+	return parts[0], parts[1], nil
+}
 
-	// Append a random query string to bypass Cloudflare's cache
-	randomQueryString := fmt.Sprintf("?nocache=%d", rand.Intn(1000000))
-	url += randomQueryString
+// RunArgs is what a single run was asked to do, as parsed from the command line.
+type RunArgs struct {
+	Init  bool
+	Quiet bool
+	// Don't send anything this run. Changes are still saved and count towards the exit code.
+	NoNotify bool
+	// Don't write anything either: no hashes, snapshots, events or notifications.
+	DryRun bool
+	// Print the diff of every change against the last snapshot, with --dry-run-diff.
+	PrintDiffs bool
+	// Send a HEAD request first, and skip downloading pages whose ETag, Last-Modified and Content-Length are the same as on the last check.
+	HeadFirst bool
+	// Make requests conditional on the cheapest validator each target's server offers, see chooseValidator.
+	Conditional bool
+	// Number of checks to keep the size and hash of in the history of every target, see SizePoint. 0 leaves histories as they are.
+	HistoryLength int
+	// Word the first change of a target that gets real content as the confirmation that it's now tracked.
+	NotifyOnFirstSeen bool
+	// Download and hash the content anyway on every Nth check, even when a 304 or --head-first says it didn't change, for servers whose ETags can't be trusted. 0 to always trust them.
+	VerifyEvery int
+	// Templates of the notifications' messages from the config, by event. nil to keep the default wording.
+	Messages MessageTemplates
+	// Where changes go for targets without a `notify` route. nil if nowhere.
+	Notifier Notifier
+	// Named notifiers from the config file.
+	Routes map[string]Notifier
+	// Notifiers from the config file that notifications of a severity also go to, see severityNotifier.
+	SeverityRoutes map[Severity]Notifier
+	// Hashes every target was last notified of, to not notify of them again. nil without --notified-file.
+	Notified *NotifiedLog
+	// Holds the change notifications of targets with a group, to send one per group at the end of the run.
+	Groups *GroupNotifications
+	// Window during which notifications are held back, nil without --quiet-hours.
+	QuietHours *QuietHours
+	Events     *EventLog
+	// Where the run prints its progress for the user, warnings and errors going to stderr regardless. nil for stdout.
+	Output io.Writer
+	// Collects the changes for the --changelog-file. nil to not write one.
+	Changelog *Changelog
+	// Used for targets that don't set their own selectorType.
+	SelectorType string
+	// Hash text exactly as it's laid out in the html source, instead of the way a browser renders it.
+	RawText bool
+	// After this many failures in a row a target is reported as dead. 0 to never.
+	FailThreshold int
+	// How long a target that failed once is left alone for, doubling with every further failure up to BackoffMax. 0 to always retry.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// How many more times to try pages whose host failed to resolve.
+	DNSRetries int
+	// Most requests in flight to a single host at any time, 0 for no limit other than the global concurrency.
+	PerHostConcurrency int
+	// SOCKS5 proxy for the targets that don't set their own.
+	SOCKS5 string
+	// DNS server for the targets that don't set their own, empty for the system's resolver.
+	DNSServer    string
+	MaxRedirects int
+	// Follow <meta http-equiv="refresh"> redirects too.
+	FollowMetaRefresh bool
+	// Don't add the nocache parameter to the urls of any target.
+	NoCacheBuster bool
+	// Statuses pages and feeds are hashed with, 200 alone when empty.
+	AcceptStatus StatusList
+	// Hosts to accept any TLS certificate of, as with a target's insecureSkipVerify.
+	InsecureHosts []string
+	// Connection pool tuning, see FetchOptions.
+	MaxIdleConns    int
+	MaxConnsPerHost int
+	DisableHTTP2    bool
+	// Fetches that receive nothing for this long are given up on, see FetchOptions.
+	ReadStallTimeout time.Duration
+	// Command that extracts the content of targets without their own extractor, see runExtractor. Empty to use the selectors.
+	Extractor        string
+	ExtractorTimeout time.Duration
+	// Headless browser command that screenshot targets are rendered with. Defaults to chromium.
+	Browser string
+	// For the GitHub API, to get its higher rate limit. Empty to go without.
+	GitHubToken string
+	// Pages fetched so far in this run, set up by checkAll.
+	Docs *DocCache
+	// Where to keep the content of every version of the targets. nil to not keep it.
+	Snapshots *SnapshotStore
+	// Changes smaller than either of these update the hash without notifying. Need Snapshots to diff against.
+	MinChangeLines int
+	MinChangeChars int
+	// Changes leaving the content at least this similar to the last notified version don't count, for targets without their own similarityThreshold. 0 to count every change.
+	SimilarityThreshold float64
+	// Warn about targets whose content is shorter than this many characters, as that's usually a broken selector or an error page. 0 to not.
+	WarnThresholdChars int
+}
 
-	resp, err := http.Get(url)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "Failed to fetch content from %s. Skipping...\n", url)
-		return
-	}
-	defer resp.Body.Close()
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing the HTML from %s. Skipping...\n", url)
-		return
+// output is Output, or stdout when it isn't set.
+func (args RunArgs) output() io.Writer {
+	if args.Output == nil {
+		return os.Stdout
 	}
-	contentBlock := ""
-	doc.Find(htmlClass).Each(func(i int, s *goquery.Selection) {
-		contentBlock += s.Text()
-	})
+	return args.Output
+}
 
-	if init {
-		newlineCount := strings.Count(contentBlock, "\n")
-		fmt.Printf("Number of newlines in contentBlock for URL %s: %d\n", url, newlineCount)
-		return
+func (args RunArgs) fetchOptions(pageURL string, entry *Entry) FetchOptions {
+	opts := FetchOptions{
+		Resolve:           entry.Resolve,
+		SOCKS5:            args.SOCKS5,
+		MaxRedirects:      args.MaxRedirects,
+		FollowMetaRefresh: args.FollowMetaRefresh,
+		AcceptStatus:      args.AcceptStatus,
+		MaxIdleConns:      args.MaxIdleConns,
+		MaxConnsPerHost:   args.MaxConnsPerHost,
+		DisableHTTP2:      args.DisableHTTP2,
+		ReadStallTimeout:  args.ReadStallTimeout,
 	}
-
-	newHash := getSHA256Hash(contentBlock)
-	oldHash := hashes[key]
-	if oldHash == "" || oldHash != newHash {
-		fmt.Fprintf(os.Stderr, "Content changed for URL: %s\n", url)
-		if tgArgs.BotToken != "" && tgArgs.ChatId != 0 {
-			utils.Msg(tgArgs.BotToken, tgArgs.ChatId, fmt.Sprintf("Content changed for URL: %s\n", url))
+	if entry.SOCKS5 != "" {
+		opts.SOCKS5 = entry.SOCKS5
+	}
+	opts.DNSServer = args.DNSServer
+	if entry.DNSServer != "" {
+		opts.DNSServer = entry.DNSServer
+	}
+	opts.InsecureSkipVerify = entry.InsecureSkipVerify
+	opts.Accept = entry.Accept
+	opts.JSON, opts.YAML = entry.Format == "json", entry.Format == "yaml"
+	opts.Range = entry.Range
+	opts.NoCacheBuster = args.NoCacheBuster || entry.NoCacheBuster
+	if len(args.InsecureHosts) > 0 {
+		if parsed, err := url.Parse(pageURL); err == nil && slices.Contains(args.InsecureHosts, parsed.Hostname()) {
+			opts.InsecureSkipVerify = true
 		}
-		hashes[key] = newHash
 	}
+	return opts
 }
 
-type TgArgs struct {
-	BotToken string
-	ChatId   int64
+func (args RunArgs) extractorFor(entry *Entry) string {
+	if entry.Extractor != "" {
+		return entry.Extractor
+	}
+	return args.Extractor
 }
 
-func NewTgArgs(input string) (TgArgs, error) {
-	if input == "" {
-		return TgArgs{}, nil
+func (args RunArgs) similarityThreshold(entry *Entry) float64 {
+	if entry.SimilarityThreshold != 0 {
+		return entry.SimilarityThreshold
 	}
-
-	parts := strings.Split(input, ",")
-	if len(parts) != 2 {
-		return TgArgs{}, fmt.Errorf("expected input format 'token,chatID', got: %s", input)
+	if entry.Fetcher == "screenshot" && args.SimilarityThreshold == 0 {
+		return defaultScreenshotSimilarity
 	}
+	return args.SimilarityThreshold
+}
 
-	chatId, err := strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
-		return TgArgs{}, fmt.Errorf("invalid chat ID: %s", parts[1])
+func (args RunArgs) extraction(entry *Entry) Extraction {
+	extraction := Extraction{
+		SelectorType:     entry.SelectorType,
+		RawText:          args.RawText,
+		OrderInsensitive: entry.OrderInsensitive,
+		ItemSelector:     entry.ItemSelector,
+		Regex:            entry.ExtractRegex,
+		Transforms:       entry.Transforms,
+		StartAnchor:      entry.StartAnchor,
+		EndAnchor:        entry.EndAnchor,
+		MatchIndex:       entry.MatchIndex,
 	}
+	if extraction.SelectorType == "" {
+		extraction.SelectorType = args.SelectorType
+	}
+	return extraction
+}
 
-	return TgArgs{
-		BotToken: parts[0],
-		ChatId:   chatId,
-	}, nil
+// notifierFor is where a notification of the target goes: its own route or the global notifier, and the route of the severity.
+func (args RunArgs) notifierFor(entry *Entry, severity Severity) Notifier {
+	if args.NoNotify || args.DryRun {
+		return nil
+	}
+	notifier := args.Notifier
+	if entry.Notify != "" {
+		notifier = args.Routes[entry.Notify]
+	}
+	if route := args.severityNotifier(severity); route != nil {
+		if notifier == nil {
+			notifier = route
+		} else {
+			notifier = Notifiers{notifier, route}
+		}
+	}
+	if notifier == nil || severity == Critical || !args.QuietHours.Contains(time.Now()) {
+		return notifier
+	}
+	if args.QuietHours.Suppress {
+		return nil
+	}
+	return queueNotifier{queue: args.QuietHours.Queue, route: entry.Notify}
 }
 
-func runApplication(c *cli.Context) error {
-	initFlag := c.Command.Name == "init"
-	if initFlag {
-		fmt.Println("Initializing Hashes...")
+// Status is what happened to a single target during a run.
+type Status int
+
+const (
+	Unchanged Status = iota
+	Changed
+	// Changed, but by less than --min-change-lines/--min-change-chars, so it isn't reported.
+	Minor
+	Skipped
+	// Turned off with `enabled: false`.
+	Disabled
+	Failed
+)
+
+// writeChanges checks a single target and updates its entry. Changes come with a record of what kind they are when there's a snapshot to compare with.
+func writeChanges(ctx context.Context, hashes Hashes, key string, args RunArgs) (Status, *ChangeRecord, error) {
+	entry := hashes[key]
+	target, err := newTarget(key, entry)
+	if err != nil {
+		return Failed, nil, err
+	}
+	url, htmlClass := target.URL, target.Selector
+	id := targetID(key, entry)
+	if !entry.IsEnabled() {
+		return Disabled, nil, nil
 	}
 
-	tgInfo := c.String("telegram")
-	var tgArgs TgArgs
-	var err error
+	if !args.Init && entry.MinInterval != 0 && entry.LastChecked != nil {
+		since := time.Since(*entry.LastChecked)
+		if since < time.Duration(entry.MinInterval) {
+			if !args.Quiet {
+				fmt.Fprintf(args.output(), "Skipping %s, checked %s ago\n", url, since.Round(time.Second))
+			}
+			return Skipped, nil, nil
+		}
+	}
+	if !args.Init && entry.Schedule != "" && entry.LastChecked != nil {
+		// Already validated on load.
+		schedule, _ := parseSchedule(entry.Schedule)
+		if due := schedule.Next(*entry.LastChecked); due.IsZero() || time.Now().Before(due) {
+			if !args.Quiet {
+				fmt.Fprintf(args.output(), "Skipping %s, not due until %s\n", url, due.Local().Format(time.DateTime))
+			}
+			return Skipped, nil, nil
+		}
+	}
+	if !args.Init && entry.NextCheck != nil && time.Now().Before(*entry.NextCheck) {
+		if !args.Quiet {
+			fmt.Fprintf(args.output(), "Skipping %s, backing off after %d failures in a row until %s\n", url, entry.ConsecutiveFailures, entry.NextCheck.Local().Format(time.DateTime))
+		}
+		return Skipped, nil, nil
+	}
+
+	// Every --verify-every checks, the content is downloaded regardless of what the server says, in case it says so wrongly.
+	verify := args.VerifyEvery > 0 && entry.UnverifiedChecks+1 >= args.VerifyEvery
+	// Only the page itself is looked at, so not for targets whose content comes from other pages too.
+	var headFingerprint string
+	// A conditional GET is as cheap as a HEAD when the server answers it with a 304.
+	conditional := args.conditionalFor(target) && (entry.Validator == validatorETag || entry.Validator == validatorLastModified)
+	if args.HeadFirst && !args.Init && !conditional && entry.NextSelector == "" && entry.InnerSelector == "" && !entry.FollowAsset {
+		// A failed HEAD, ex. a server not supporting it, just falls through to the GET.
+		headFingerprint, _ = args.Docs.Head(ctx, url, args.fetchOptions(url, entry))
+		if headFingerprint != "" && headFingerprint == entry.HeadFingerprint && entry.Hash != "" && !verify {
+			now := time.Now()
+			entry.LastChecked = &now
+			entry.UnverifiedChecks++
+			return Unchanged, nil, nil
+		}
+	}
 
-	tgArgs, err = NewTgArgs(tgInfo)
+	if !args.Init && entry.Hash != "" && !verify {
+		target.ETag, target.LastModified = entry.ETag, entry.LastModified
+	}
+	contentBlock, meta, err := args.fetcherFor(target).Fetch(ctx, target)
 	if err != nil {
-		return err
+		return Failed, nil, err
+	}
+	if meta.NotModified {
+		now := time.Now()
+		entry.LastChecked = &now
+		entry.UnverifiedChecks++
+		return Unchanged, nil, nil
+	}
+	if chars := utf8.RuneCountInString(contentBlock); chars < args.WarnThresholdChars {
+		fmt.Fprintf(os.Stderr, "[%s] Only %d characters of content from %s (%s), under --warn-threshold-chars %d; the selector may be broken or the page an error\n", id, chars, url, htmlClass, args.WarnThresholdChars)
+	}
+	if args.HeadFirst && !args.Init {
+		entry.HeadFingerprint = headFingerprint
 	}
 
-	defaultPath := "~/tmp/doc_scraper_hashes.json"
-	filePath := c.String("path")
-	if filePath == "" {
-		filePath = defaultPath
+	if args.Init {
+		if args.Quiet {
+			return Unchanged, nil, nil
+		}
+		newlineCount := strings.Count(contentBlock, "\n")
+		fmt.Fprintf(args.output(), "Number of newlines in contentBlock for URL %s: %d\n", url, newlineCount)
+		return Unchanged, nil, nil
 	}
-	if strings.HasPrefix(filePath, "~") {
-		homeDir, err := os.UserHomeDir()
+
+	now := time.Now()
+	newHash := getSHA256Hash(contentBlock)
+	old := *entry
+	args.recordFetch(entry, target, contentBlock, meta, verify, now)
+	oldHash := old.Hash
+	var previous string
+	var hasPrevious bool
+	if args.Snapshots != nil {
+		previous, hasPrevious, err = args.Snapshots.Latest(key)
 		if err != nil {
-			fmt.Println("Error getting user home directory:", err)
-			return err
+			fmt.Fprintf(os.Stderr, "Failed to read the previous snapshot of %s: %v\n", url, err)
+		}
+		if (!hasPrevious || previous != contentBlock) && !args.DryRun {
+			if err := args.Snapshots.Save(key, contentBlock, now); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save a snapshot of %s: %v\n", url, err)
+			}
+			if meta.Screenshot != nil {
+				if err := args.Snapshots.SaveImage(key, meta.Screenshot, now); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save the screenshot of %s: %v\n", url, err)
+				}
+			}
 		}
-		filePath = homeDir + filePath[1:]
 	}
 
-	originalHashes, err := loadHashes(filePath)
-	if err != nil {
-		return err
+	var newSimHash uint64
+	threshold := args.similarityThreshold(entry)
+	if threshold > 0 {
+		newSimHash = simHash(contentBlock)
+		if meta.Screenshot != nil {
+			newSimHash = meta.VisualHash
+		}
+		if entry.SimHash == "" && oldHash == newHash {
+			entry.SimHash = formatSimHash(newSimHash)
+		}
 	}
-	hashes := make(Hashes, len(originalHashes))
-	for k, v := range originalHashes {
-		hashes[k] = v
+
+	// Feeds are notified of by their new items, rather than by any change.
+	var freshItems []FeedItem
+	isFeed := meta.Items != nil
+	hadSeenItems := old.SeenItems != nil
+	if isFeed {
+		freshItems = newFeedItems(old.SeenItems, meta.Items)
 	}
-	for key := range hashes {
-		writeChanges(hashes, key, initFlag, tgArgs)
+
+	if oldHash == "" || oldHash != newHash {
+		if args.PrintDiffs {
+			printDiff(args.output(), id, url, htmlClass, previous, hasPrevious, contentBlock)
+		}
+		entry.Hash = newHash
+		entry.LastChanged = &now
+		entry.StaleNotified = false
+		if isFeed && hadSeenItems && len(freshItems) == 0 {
+			if !args.Quiet {
+				fmt.Fprintf(args.output(), "Feed %s changed, but has no new items. Not notifying\n", url)
+			}
+			return Minor, nil, nil
+		}
+		var change *ChangeRecord
+		if hasPrevious {
+			record := classifyChange(previous, contentBlock)
+			change = &record
+		}
+		if threshold > 0 && entry.SimHash != "" {
+			// Against the last version that was notified of rather than the previous one, so that small changes still add up to a notification.
+			notified, _ := parseSimHash(entry.SimHash)
+			if similarity := simHashSimilarity(notified, newSimHash); similarity >= threshold {
+				if !args.Quiet {
+					fmt.Fprintf(args.output(), "Content changed for URL: %s, but is still %.0f%% similar. Not notifying\n", url, similarity*100)
+				}
+				return Minor, change, nil
+			}
+		}
+		if hasPrevious {
+			if size := measureDiff(previous, contentBlock); size.Lines < args.MinChangeLines || size.Chars < args.MinChangeChars {
+				if !args.Quiet {
+					fmt.Fprintf(args.output(), "Content changed for URL: %s, but only by %d lines, %d characters. Not notifying\n", url, size.Lines, size.Chars)
+				}
+				return Minor, change, nil
+			}
+		}
+		if threshold > 0 {
+			entry.SimHash = formatSimHash(newSimHash)
+		}
+
+		msg, event := fmt.Sprintf("[%s] Content changed for URL: %s", id, url), "changed"
+		switch {
+		case args.NotifyOnFirstSeen && (oldHash == "" || oldHash == getSHA256Hash("")) && contentBlock != "":
+			// The first real content of a new target, ex. one just imported, confirming it's tracked rather than a change.
+			msg, event = fmt.Sprintf("[%s] Now tracking URL: %s (%d characters)", id, url, utf8.RuneCountInString(contentBlock)), "first-seen"
+		case isFeed && hadSeenItems:
+			event = "new-items"
+			msg = fmt.Sprintf("[%s] %d new items in feed %s:", id, len(freshItems), url)
+			for _, item := range freshItems {
+				msg += fmt.Sprintf("\n%s %s", item.Title, item.Link)
+			}
+		}
+		if changedSelectors := diffSelectors(old.Selectors, entry.Selectors); len(changedSelectors) > 0 {
+			msg += fmt.Sprintf(" (selectors: %s)", strings.Join(changedSelectors, ", "))
+		}
+		operations := diffOperations(old.Operations, entry.Operations)
+		if !operations.Empty() {
+			msg += "\n" + operations.String()
+		}
+		if meta.Unmatched != nil {
+			msg += fmt.Sprintf(", %s matched nothing", htmlClass)
+			// The last snapshot is what the selector used to match, to look for on the redesigned page.
+			if suggestions := suggestSelectors(meta.Unmatched, previous); len(suggestions) > 0 {
+				msg += fmt.Sprintf(", did you mean '%s'?", strings.Join(suggestions, "' or '"))
+			}
+		}
+		if change != nil && change.NumericChange {
+			msg += "\nNumbers changed:\n" + change.summary()
+		}
+		fmt.Fprintln(os.Stderr, msg)
+		args.Changelog.Add(ChangelogEntry{Key: key, ID: id, Diff: diffLines(previous, contentBlock), HasPrevious: hasPrevious})
+		severity := changeSeverity(entry)
+		if notifier := args.notifierFor(entry, severity); notifier != nil && args.Notified.Notified(key, newHash) {
+			if !args.Quiet {
+				fmt.Fprintf(args.output(), "Already notified of this version of %s. Not notifying again\n", url)
+			}
+		} else if notifier != nil {
+			data := MessageData{
+				Event:     event,
+				ID:        id,
+				URL:       url,
+				Selector:  htmlClass,
+				Group:     entry.Group,
+				OldHash:   oldHash,
+				NewHash:   newHash,
+				Timestamp: now,
+				Default:   msg,
+			}
+			if hasPrevious {
+				data.DiffSummary = diffSummary(change, previous, contentBlock)
+			}
+			if event == "new-items" {
+				data.Items = freshItems
+			}
+			notification := Notification{
+				Message:  args.Messages.render(data),
+				Severity: severity,
+				ID:       id,
+				URL:      url,
+				Selector: htmlClass,
+				OldHash:  oldHash,
+				NewHash:  newHash,
+				Change:   change,
+			}
+			if !operations.Empty() {
+				notification.Operations = &operations
+			}
+			if args.Groups.Hold(entry.Group, entry.Notify, key, notifier, notification) {
+				// Sent along with the rest of the group at the end of the run.
+			} else if err := deliver(ctx, notifier, notification, map[string]string{key: newHash}, args); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to send notification for %s: %v\n", url, err)
+			}
+		}
+		return Changed, change, nil
 	}
-	err = saveHashes(filePath, hashes)
-	if err != nil {
-		return err
+	return Unchanged, nil, nil
+}
+
+// recordFetch updates the entry with a fetch of its target that got content, all but the content's hash, which is for the caller to take as a change or not:
+// the server's validators, when it was checked, what the selectors matched, the spec's operations and the feed's items.
+func (args RunArgs) recordFetch(entry *Entry, target Target, content string, meta FetchMeta, verify bool, now time.Time) {
+	id := targetID(target.Key, entry)
+	oldETag, oldLastModified := entry.ETag, entry.LastModified
+	entry.ETag, entry.LastModified = meta.ETag, meta.LastModified
+	entry.UnverifiedChecks = 0
+	entry.Matches = meta.Matches
+	entry.LastChecked = &now
+	entry.Canonical = meta.Canonical
+	if entry.LastChanged == nil {
+		entry.LastChanged = &now
+	}
+
+	newHash := getSHA256Hash(content)
+	oldHash := entry.Hash
+	if args.HistoryLength > 0 {
+		entry.recordSize(SizePoint{Time: now, Size: utf8.RuneCountInString(content), Hash: newHash}, args.HistoryLength)
+	}
+	if args.conditionalFor(target) {
+		validator, distrusted := chooseValidator(entry.Validator, meta, oldETag, oldLastModified, oldHash != "" && oldHash != newHash)
+		if distrusted {
+			fmt.Fprintf(os.Stderr, "[%s] Content of %s changed while its %s didn't; checking it by %s from now on\n", id, target.URL, entry.Validator, validator)
+		}
+		entry.Validator = validator
+	} else if verify && oldHash != "" && oldHash != newHash && meta.ETag != "" && meta.ETag == oldETag {
+		fmt.Fprintf(os.Stderr, "[%s] Content of %s changed while its ETag %s didn't; the server's 304s can't be trusted, consider a lower --verify-every\n", id, target.URL, meta.ETag)
+	}
+	entry.Selectors = meta.Selectors
+	entry.Operations = meta.Operations
+	if meta.Items != nil {
+		entry.SeenItems = make([]string, len(meta.Items))
+		for i, item := range meta.Items {
+			entry.SeenItems[i] = item.ID
+		}
+	}
+}
+
+// Returns the selectors whose hash differs between old and new, sorted. Nothing if there is no previous per-selector state to compare against.
+func diffSelectors(old, new map[string]string) []string {
+	if len(old) == 0 {
+		return nil
+	}
+	var changed []string
+	for sel, hash := range new {
+		if old[sel] != hash {
+			changed = append(changed, sel)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// RunReport sums up a pass over the targets.
+type RunReport struct {
+	// Keys whose content changed.
+	Changed []string
+	// Number of targets actually fetched and compared.
+	Checked int
+	// Number of targets left for later, ex. because of minInterval.
+	Skipped int
+	// Keys of the targets that are turned off.
+	Disabled []string
+	// Keys of the targets whose TLS handshake failed, which --insecure-skip-verify could get past.
+	TLSFailed []string
+	// Number of targets the run didn't get to before --run-timeout.
+	NotReached int
+	TimedOut   bool
+	// All the errors encountered along the way, joined together.
+	Err error
+}
+
+// Exit codes of a check, so that scripts and CI can tell the outcomes apart.
+const (
+	ExitUnchanged = 0
+	ExitChanged   = 1
+	// Some targets failed to be fetched or parsed, only with --fail-on-error.
+	ExitErrors  = 2
+	ExitTimeout = 3
+	// Bad flags or config, or anything else that stopped the run before it got to the targets.
+	ExitUsage = 4
+)
+
+// ExitCode is what the run exits with. When several apply, a timeout wins over errors, which win over changes,
+// since the changes found are already saved and notified either way.
+func (r RunReport) ExitCode(init, failOnError bool) int {
+	switch {
+	case r.TimedOut:
+		return ExitTimeout
+	case failOnError && r.Err != nil:
+		return ExitErrors
+	case !init && len(r.Changed) > 0:
+		return ExitChanged
+	default:
+		return ExitUnchanged
 	}
+}
 
-	if !initFlag {
-		for key := range hashes {
-			if hashes[key] != originalHashes[key] {
-				os.Exit(1)
+// checkAll runs writeChanges over every key, at most `concurrency` at a time, highest priority first.
+// Once ctx is done no new fetches are started, and the ones in flight are abandoned.
+func checkAll(ctx context.Context, hashes Hashes, args RunArgs, concurrency int) RunReport {
+	var (
+		mu     sync.Mutex
+		report RunReport
+		errs   []error
+	)
+	args.Docs = NewDocCache(args.DNSRetries, args.PerHostConcurrency)
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for _, key := range dispatchOrder(hashes) {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				report.NotReached++
+				return nil
+			}
+			oldHash := hashes[key].Hash
+			status, change, err := writeChanges(ctx, hashes, key, args)
+			if err != nil && ctx.Err() != nil {
+				// Cut off midway by the run timeout, which isn't the target's fault.
+				mu.Lock()
+				defer mu.Unlock()
+				report.NotReached++
+				return nil
+			}
+			if !args.Init {
+				args.Events.Record(key, targetID(key, hashes[key]), status, oldHash, hashes[key].Hash, change, err)
+				trackFailures(ctx, hashes[key], key, status, err, args)
+				trackStaleness(ctx, hashes[key], key, status, args)
 			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch status {
+			case Changed:
+				report.Changed = append(report.Changed, key)
+				report.Checked++
+			case Unchanged, Minor:
+				report.Checked++
+			case Skipped:
+				report.Skipped++
+			case Disabled:
+				report.Disabled = append(report.Disabled, key)
+			}
+			if err != nil {
+				errs = append(errs, err)
+				if errors.Is(err, ErrTLS) {
+					report.TLSFailed = append(report.TLSFailed, key)
+				}
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	report.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+	report.Err = errors.Join(errs...)
+	return report
+}
+
+// Fetches are mostly waiting on the network, so there can be several per CPU.
+const fetchesPerCPU = 4
+
+// autoConcurrency is the --concurrency-auto for the enabled targets: a few fetches per CPU, but no more than their hosts can take at once
+// with the per-host limit (or two each without one), so that a run over a handful of hosts doesn't start workers that would only wait on the limiter.
+func autoConcurrency(hashes Hashes, perHostConcurrency int) (concurrency, hosts int) {
+	distinct := make(map[string]bool)
+	for key, entry := range hashes {
+		if !entry.IsEnabled() {
+			continue
 		}
+		pageURL, _, _ := splitKey(key)
+		if parsed, err := url.Parse(pageURL); err == nil {
+			distinct[parsed.Hostname()] = true
+		}
+	}
+	if perHostConcurrency <= 0 {
+		perHostConcurrency = 2
 	}
+	concurrency = min(runtime.NumCPU()*fetchesPerCPU, len(distinct)*perHostConcurrency)
+	return max(concurrency, 1), len(distinct)
+}
 
-	return nil
+// dispatchOrder is the keys by descending priority, and alphabetically among the same priority so that runs are reproducible.
+func dispatchOrder(hashes Hashes) []string {
+	keys := make([]string, 0, len(hashes))
+	for key := range hashes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if pi, pj := hashes[keys[i]].Priority, hashes[keys[j]].Priority; pi != pj {
+			return pi > pj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
 }
 
-func main() {
-	app := cli.NewApp()
-	app.Name = "doc_scraper"
-	app.Usage = "Stupid little thing to catch exchange documentation changes."
-	app.Commands = []cli.Command{
-		{
-			Name:   "check",
-			Usage:  "Loads hashes and url:htmlClass from specified --path",
-			Action: runApplication,
-			Flags: []cli.Flag{
-				&cli.StringFlag{
-					Name:  "telegram",
-					Usage: "Telegram bot token and chat ID to receive notification on; format: 'token,chatID'. Ex: '123456:ABC-DEF1234ghIkl-zyx57W2,-1234567890'",
-				},
-				&cli.StringFlag{
-					Name:  "path",
-					Usage: "Path to the hashes.json file, default '~/tmp/doc_scraper_hashes.json'",
-				},
-			},
+// trackFailures keeps the entry's ConsecutiveFailures up to date, and notifies once it reaches the threshold, so that permanently broken targets stand out from one-off blips.
+func trackFailures(ctx context.Context, entry *Entry, key string, status Status, checkErr error, args RunArgs) {
+	switch status {
+	case Changed, Minor, Unchanged:
+		entry.ConsecutiveFailures = 0
+		entry.NextCheck = nil
+		return
+	case Failed:
+		entry.ConsecutiveFailures++
+		if backoff := args.backoff(entry.ConsecutiveFailures); backoff > 0 {
+			next := time.Now().Add(backoff)
+			entry.NextCheck = &next
+		}
+	default:
+		return
+	}
+	if args.FailThreshold <= 0 || entry.ConsecutiveFailures != args.FailThreshold {
+		return
+	}
+
+	url, htmlClass, _ := splitKey(key)
+	id := targetID(key, entry)
+	msg := fmt.Sprintf("[%s] Target appears dead, failed %d checks in a row: %s\nLast error: %v", id, entry.ConsecutiveFailures, url, checkErr)
+	fmt.Fprintln(os.Stderr, msg)
+	if notifier := args.notifierFor(entry, Critical); notifier != nil {
+		data := MessageData{
+			Event:     "dead",
+			ID:        id,
+			URL:       url,
+			Selector:  htmlClass,
+			Group:     entry.Group,
+			OldHash:   entry.Hash,
+			Timestamp: time.Now(),
+			Failures:  entry.ConsecutiveFailures,
+			Error:     fmt.Sprint(checkErr),
+			Default:   msg,
+		}
+		notification := Notification{
+			Message:  args.Messages.render(data),
+			Severity: Critical,
+			ID:       id,
+			URL:      url,
+			Selector: htmlClass,
+			OldHash:  entry.Hash,
+		}
+		if err := notifier.Notify(ctx, notification); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send notification for %s: %v\n", url, err)
+		}
+	}
+}
+
+// backoff is how long to leave a target alone for after that many failures in a row: BackoffBase, doubled for every failure past the first, up to BackoffMax.
+func (args RunArgs) backoff(failures int) time.Duration {
+	if args.BackoffBase <= 0 || failures < 1 {
+		return 0
+	}
+	backoff := args.BackoffBase
+	for i := 1; i < failures && backoff < args.BackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, args.BackoffMax)
+}
+
+// trackStaleness notifies once the content of a target with expectChangeWithin has gone without changing for longer than that.
+func trackStaleness(ctx context.Context, entry *Entry, key string, status Status, args RunArgs) {
+	if status != Unchanged || entry.ExpectChangeWithin == 0 || entry.LastChanged == nil || entry.StaleNotified {
+		return
+	}
+	since := time.Since(*entry.LastChanged)
+	if since < time.Duration(entry.ExpectChangeWithin) {
+		return
+	}
+	entry.StaleNotified = true
+
+	url, htmlClass, _ := splitKey(key)
+	id := targetID(key, entry)
+	msg := fmt.Sprintf("[%s] Content is stale, hasn't updated in %s: %s", id, since.Round(time.Minute), url)
+	fmt.Fprintln(os.Stderr, msg)
+	if notifier := args.notifierFor(entry, Warn); notifier != nil {
+		data := MessageData{
+			Event:     "stale",
+			ID:        id,
+			URL:       url,
+			Selector:  htmlClass,
+			Group:     entry.Group,
+			OldHash:   entry.Hash,
+			Timestamp: time.Now(),
+			Since:     since.Round(time.Minute),
+			Default:   msg,
+		}
+		notification := Notification{
+			Message:  args.Messages.render(data),
+			Severity: Warn,
+			ID:       id,
+			URL:      url,
+			Selector: htmlClass,
+			OldHash:  entry.Hash,
+		}
+		if err := notifier.Notify(ctx, notification); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send notification for %s: %v\n", url, err)
+		}
+	}
+}
+
+type TgArgs struct {
+	BotToken string
+	ChatIds  []int64
+}
+
+// NewTgArgs parses 'token,chatID', or 'token,chat1;chat2;chat3' to send to several chats. So that the token doesn't have to show up in `ps` or shell history, it can also be given as
+// 'env:VAR' to read it from an environment variable, or 'file:/path' to read it from a file.
+func NewTgArgs(input string) (TgArgs, error) {
+	if input == "" {
+		return TgArgs{}, nil
+	}
+
+	parts := strings.Split(input, ",")
+	if len(parts) != 2 {
+		return TgArgs{}, fmt.Errorf("expected input format 'token,chatID' or 'token,chat1;chat2', got: %s", input)
+	}
+
+	var chatIds []int64
+	for _, chat := range strings.Split(parts[1], ";") {
+		// Left empty by a trailing or doubled ';'.
+		if chat = strings.TrimSpace(chat); chat == "" {
+			continue
+		}
+		chatId, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return TgArgs{}, fmt.Errorf("invalid chat ID: %s", chat)
+		}
+		chatIds = append(chatIds, chatId)
+	}
+	if len(chatIds) == 0 {
+		return TgArgs{}, fmt.Errorf("no chat ID in %q", parts[1])
+	}
+
+	botToken, err := resolveSecret(parts[0])
+	if err != nil {
+		return TgArgs{}, err
+	}
+
+	return TgArgs{
+		BotToken: botToken,
+		ChatIds:  chatIds,
+	}, nil
+}
+
+// resolveSecret reads 'env:VAR' and 'file:/path' references, anything else is returned as is.
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "file:"):
+		filePath, err := expandHome(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", err
+		}
+		secret, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimSpace(string(secret)), nil
+	default:
+		return value, nil
+	}
+}
+
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Println("Error getting user home directory:", err)
+		return "", err
+	}
+	return homeDir + path[1:], nil
+}
+
+// insecureHosts is the list of --insecure-skip-verify.
+func insecureHosts(c *cli.Context) []string {
+	var hosts []string
+	for _, host := range strings.Split(c.String("insecure-skip-verify"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hashesPath is the --path of the hashes file, or the default one.
+func hashesPath(c *cli.Context) (string, error) {
+	defaultPath := "~/tmp/doc_scraper_hashes.json"
+	filePath := c.String("path")
+	if filePath == "" {
+		filePath = defaultPath
+	}
+	return expandHome(filePath)
+}
+
+// loadNotifiers sets up where notifications go, from the notifier flags and the --config, which it returns along with its path.
+func (args *RunArgs) loadNotifiers(c *cli.Context) (Config, string, error) {
+	tgInfo := c.String("telegram")
+	tgArgs, err := NewTgArgs(tgInfo)
+	if err != nil {
+		return Config{}, "", err
+	}
+	var globalNotifiers Notifiers
+	if tgArgs.BotToken != "" && len(tgArgs.ChatIds) > 0 {
+		globalNotifiers = append(globalNotifiers, TelegramNotifier{TgArgs: tgArgs})
+	}
+	if onChange := c.String("on-change"); onChange != "" {
+		globalNotifiers = append(globalNotifiers, CommandNotifier{Command: onChange, Timeout: c.Duration("on-change-timeout"), Output: args.Output})
+	}
+	if topicURL := c.String("ntfy"); topicURL != "" {
+		ntfy := NtfyNotifier{TopicURL: topicURL, Priority: c.String("ntfy-priority"), Tags: c.String("ntfy-tags")}
+		if err := ntfy.validate(); err != nil {
+			return Config{}, "", fmt.Errorf("--ntfy: %w", err)
+		}
+		globalNotifiers = append(globalNotifiers, ntfy)
+	}
+	if len(globalNotifiers) > 0 {
+		args.Notifier = globalNotifiers
+	}
+
+	configPath, err := expandHome(c.String("config"))
+	if err != nil {
+		return Config{}, "", err
+	}
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return Config{}, "", err
+	}
+	if env := c.String("env"); env != "" {
+		if configPath == "" {
+			return Config{}, "", fmt.Errorf("--env needs a --config to pick the profile from")
+		}
+		config, err = config.withProfile(env)
+		if err != nil {
+			return Config{}, "", err
+		}
+	}
+	args.Routes = make(map[string]Notifier, len(config.Notifiers))
+	for name, notifierConfig := range config.Notifiers {
+		args.Routes[name], err = notifierConfig.Notifier(args.Output)
+		if err != nil {
+			return Config{}, "", fmt.Errorf("notifier %s: %w", name, err)
+		}
+	}
+	// Already validated with the config.
+	args.Messages, _ = parseMessageTemplates(config.Messages)
+	args.SeverityRoutes = make(map[Severity]Notifier, len(config.SeverityRoutes))
+	for name, route := range config.SeverityRoutes {
+		// Already validated with the config.
+		severity, _ := parseSeverity(name)
+		notifier, ok := args.Routes[route]
+		if !ok {
+			return Config{}, "", fmt.Errorf("severity %s routes to notifier %q, which isn't defined in the config", name, route)
+		}
+		args.SeverityRoutes[severity] = notifier
+	}
+	return config, configPath, nil
+}
+
+func runApplication(c *cli.Context) error {
+	stopProfile, err := startProfile(c.String("profile"), c.String("profile-file"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := stopProfile(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write the profile: %v\n", err)
+		}
+	}()
+
+	githubToken, err := resolveSecret(c.String("github-token"))
+	if err != nil {
+		return fmt.Errorf("--github-token: %w", err)
+	}
+	args := RunArgs{
+		Init:                c.Command.Name == "init",
+		Quiet:               c.Bool("quiet"),
+		NoNotify:            c.Bool("no-notify"),
+		DryRun:              c.Bool("dry-run") || c.Bool("dry-run-diff"),
+		PrintDiffs:          c.Bool("dry-run-diff"),
+		HeadFirst:           c.Bool("head-first"),
+		Conditional:         c.Bool("conditional"),
+		VerifyEvery:         c.Int("verify-every"),
+		NotifyOnFirstSeen:   c.Bool("notify-on-first-seen"),
+		HistoryLength:       c.Int("history-length"),
+		SelectorType:        c.String("selector-type"),
+		RawText:             c.Bool("raw-text"),
+		FailThreshold:       c.Int("fail-threshold"),
+		BackoffBase:         c.Duration("backoff"),
+		BackoffMax:          c.Duration("max-backoff"),
+		DNSRetries:          c.Int("dns-retries"),
+		PerHostConcurrency:  c.Int("per-host-concurrency"),
+		SOCKS5:              c.String("socks5"),
+		DNSServer:           c.String("dns-server"),
+		MaxRedirects:        c.Int("max-redirects"),
+		FollowMetaRefresh:   c.Bool("follow-meta-refresh"),
+		NoCacheBuster:       c.Bool("no-cache-buster"),
+		InsecureHosts:       insecureHosts(c),
+		GitHubToken:         githubToken,
+		MaxIdleConns:        c.Int("max-idle-conns"),
+		MaxConnsPerHost:     c.Int("max-conns-per-host"),
+		DisableHTTP2:        c.Bool("disable-http2"),
+		MinChangeLines:      c.Int("min-change-lines"),
+		MinChangeChars:      c.Int("min-change-chars"),
+		SimilarityThreshold: c.Float64("similarity-threshold"),
+		WarnThresholdChars:  c.Int("warn-threshold-chars"),
+		ReadStallTimeout:    c.Duration("read-stall-timeout"),
+		Browser:             c.String("browser"),
+		Extractor:           c.String("extractor"),
+		ExtractorTimeout:    c.Duration("extractor-timeout"),
+	}
+	if c.String("events-file") == "-" && !args.DryRun {
+		// Stdout is the events' alone, for the consumer to parse line by line: everything else the run prints, the output of --on-change included, goes to stderr.
+		args.Output = os.Stderr
+	}
+	if args.SelectorType != "css" && args.SelectorType != "xpath" {
+		return fmt.Errorf("selector type must be 'css' or 'xpath', got: %s", args.SelectorType)
+	}
+	if args.DNSServer != "" {
+		if _, err := dnsServerAddress(args.DNSServer); err != nil {
+			return fmt.Errorf("--dns-server: %w", err)
+		}
+	}
+	args.AcceptStatus, err = parseStatusList(c.String("accept-status"))
+	if err != nil {
+		return fmt.Errorf("--accept-status: %w", err)
+	}
+	if args.Init && !args.Quiet && c.String("print-config") == "" {
+		fmt.Fprintln(args.output(), "Initializing Hashes...")
+	}
+
+	config, configPath, err := args.loadNotifiers(c)
+	if err != nil {
+		return err
+	}
+
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	if c.String("path") == "" && config.Path != "" {
+		filePath, err = expandHome(config.Path)
+		if err != nil {
+			return err
+		}
+	}
+	if format := c.String("print-config"); format != "" {
+		if format != "json" && format != "yaml" {
+			return fmt.Errorf("--print-config must be 'json' or 'yaml', got: %s", format)
+		}
+		return writeOutput(os.Stdout, format, newEffectiveConfig(c, filePath, configPath, config), nil)
+	}
+
+	snapshotDir := c.String("snapshot-dir")
+	if snapshotDir == "" {
+		snapshotDir = config.SnapshotDir
+	}
+	if snapshotDir != "" {
+		snapshotDir, err = expandHome(snapshotDir)
+		if err != nil {
+			return err
+		}
+		args.Snapshots = &SnapshotStore{Dir: snapshotDir}
+	} else if args.MinChangeLines > 0 || args.MinChangeChars > 0 {
+		return fmt.Errorf("--min-change-lines and --min-change-chars need --snapshot-dir, to have something to diff against")
+	} else if args.PrintDiffs {
+		return fmt.Errorf("--dry-run-diff needs --snapshot-dir, to have something to diff against")
+	}
+
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	var base StoreBase
+	if c.Bool("merge-on-save") {
+		base, err = newStoreBase(hashes)
+		if err != nil {
+			return err
+		}
+	}
+	// Template targets that aren't tracked yet are added; the ones that are keep their state and options.
+	for key, entry := range config.templateTargets() {
+		if _, ok := hashes[key]; !ok {
+			hashes[key] = entry
+		}
+	}
+	for key, entry := range hashes {
+		if _, ok := args.Routes[entry.Notify]; entry.Notify != "" && !ok {
+			return fmt.Errorf("target %q routes to notifier %q, which isn't defined in the config", key, entry.Notify)
+		}
+	}
+	if args.Snapshots != nil {
+		args.Snapshots.IDs = hashes.IDs()
+	}
+	if notifiedPath := c.String("notified-file"); notifiedPath != "" && !args.DryRun {
+		notifiedPath, err = expandHome(notifiedPath)
+		if err != nil {
+			return err
+		}
+		args.Notified, err = loadNotifiedLog(notifiedPath)
+		if err != nil {
+			return fmt.Errorf("failed to read --notified-file: %w", err)
+		}
+	}
+	if window := c.String("quiet-hours"); window != "" {
+		location := time.Local
+		if timezone := c.String("timezone"); timezone != "" {
+			location, err = time.LoadLocation(timezone)
+			if err != nil {
+				return fmt.Errorf("--timezone: %w", err)
+			}
+		}
+		args.QuietHours, err = parseQuietHours(window, location)
+		if err != nil {
+			return fmt.Errorf("--quiet-hours: %w", err)
+		}
+		args.QuietHours.Suppress = c.Bool("quiet-hours-suppress")
+		args.QuietHours.Queue, err = loadNotificationQueue(filePath + ".queued.json")
+		if err != nil {
+			return fmt.Errorf("failed to read the notifications held back by --quiet-hours: %w", err)
+		}
+	}
+
+	// Nothing of a dry run is kept, and neither are its events: the next real run finds the same changes again, which would then be in the events
+	// twice, and replay-notify would send them again.
+	if eventsPath := c.String("events-file"); eventsPath != "" && !args.DryRun {
+		eventsPath, err = expandHome(eventsPath)
+		if err != nil {
+			return err
+		}
+		args.Events, err = OpenEventLog(eventsPath)
+		if err != nil {
+			return err
+		}
+		defer args.Events.Close()
+	}
+	changelogPath := c.String("changelog-file")
+	if changelogPath != "" && !args.DryRun {
+		changelogPath, err = expandHome(changelogPath)
+		if err != nil {
+			return err
+		}
+		args.Changelog = &Changelog{}
+	}
+	sincePath := c.String("since-file")
+	var marker RunMarker
+	var hasMarker bool
+	if sincePath != "" {
+		sincePath, err = expandHome(sincePath)
+		if err != nil {
+			return err
+		}
+		marker, hasMarker, err = loadRunMarker(sincePath)
+		if err != nil {
+			return fmt.Errorf("failed to read --since-file: %w", err)
+		}
+	}
+	concurrency := c.Int("concurrency")
+	if c.Bool("concurrency-auto") {
+		var hosts int
+		concurrency, hosts = autoConcurrency(hashes, args.PerHostConcurrency)
+		if !args.Quiet {
+			fmt.Fprintf(args.output(), "Checking %d targets at a time, for %d CPUs and %d hosts\n", concurrency, runtime.NumCPU(), hosts)
+		}
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got: %d", concurrency)
+	}
+	if args.SimilarityThreshold < 0 || args.SimilarityThreshold > 1 {
+		return fmt.Errorf("--similarity-threshold must be between 0 and 1, got: %v", args.SimilarityThreshold)
+	}
+	if args.BackoffBase > 0 && args.BackoffMax < args.BackoffBase {
+		return fmt.Errorf("--max-backoff can't be shorter than --backoff, got %s and %s", args.BackoffMax, args.BackoffBase)
+	}
+
+	if gap := c.Duration("min-run-gap"); gap > 0 && !args.Init {
+		if err := checkRunGap(lastRunPath(filePath), gap, c.Bool("force"), args.DryRun, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	if runTimeout := c.Duration("run-timeout"); runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
+	if quiet := args.QuietHours; quiet != nil && !args.NoNotify && !args.DryRun && !quiet.Contains(time.Now()) {
+		quiet.Queue.Flush(ctx, args)
+	}
+	args.Groups = &GroupNotifications{}
+	report := checkAll(ctx, hashes, args, concurrency)
+	// Even past --run-timeout, as the changes it found are saved either way.
+	args.Groups.Send(context.WithoutCancel(ctx), args)
+	if args.Notified != nil {
+		if err := args.Notified.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write --notified-file: %v\n", err)
+		}
+	}
+	if args.QuietHours != nil && !args.DryRun {
+		if err := args.QuietHours.Queue.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save the notifications held back by --quiet-hours: %v\n", err)
+		}
+	}
+	if report.Err != nil {
+		fmt.Fprintf(os.Stderr, "Some targets were skipped:\n%v\n", report.Err)
+	}
+	if len(report.TLSFailed) > 0 {
+		sort.Strings(report.TLSFailed)
+		fmt.Fprintf(os.Stderr, "TLS handshake failed for %d targets; if it's their certificate, passing their hosts to --insecure-skip-verify checks them anyway:\n", len(report.TLSFailed))
+		for _, key := range report.TLSFailed {
+			fmt.Fprintf(os.Stderr, "  %s\n", describeTarget(key, hashes[key]))
+		}
+	}
+	if len(report.Disabled) > 0 && !args.Quiet {
+		sort.Strings(report.Disabled)
+		fmt.Fprintf(args.output(), "Skipped %d disabled targets:\n", len(report.Disabled))
+		for _, key := range report.Disabled {
+			fmt.Fprintf(args.output(), "  %s\n", describeTarget(key, hashes[key]))
+		}
+	}
+	if sincePath != "" && !args.Init {
+		fresh := report.Changed
+		if hasMarker {
+			fresh = marker.newSince(report.Changed, hashes)
+			fmt.Fprintf(args.output(), "%d changes new since the previous run at %s:\n", len(fresh), marker.Timestamp.Local().Format(time.DateTime))
+		} else {
+			sort.Strings(fresh)
+			fmt.Fprintf(args.output(), "%d changes, no previous run to compare to:\n", len(fresh))
+		}
+		for _, key := range fresh {
+			fmt.Fprintf(args.output(), "  %s\n", describeTarget(key, hashes[key]))
+		}
+		if !args.DryRun {
+			next := RunMarker{Timestamp: time.Now(), Changed: make(map[string]string, len(report.Changed))}
+			for _, key := range report.Changed {
+				next.Changed[key] = hashes[key].Hash
+			}
+			if err := saveRunMarker(sincePath, next); err != nil {
+				return fmt.Errorf("failed to write --since-file: %w", err)
+			}
+		}
+	}
+	if err := reportCanonical(hashes, c.Bool("use-canonical"), args); err != nil {
+		return err
+	}
+	if args.DryRun {
+		if !args.Quiet {
+			fmt.Fprintf(args.output(), "Dry run, not saving %s\n", filePath)
+		}
+	} else if base != nil {
+		if err := saveMergedHashes(filePath, base, hashes, c.Bool("compact")); err != nil {
+			return err
+		}
+	} else if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+		return err
+	}
+	if err := args.Changelog.Prepend(changelogPath, time.Now()); err != nil {
+		return fmt.Errorf("failed to write --changelog-file: %w", err)
+	}
+
+	if report.TimedOut {
+		fmt.Fprintf(os.Stderr, "Run timed out after %s: checked %d targets, skipped %d, didn't get to %d\n", c.Duration("run-timeout"), report.Checked, report.Skipped, report.NotReached)
+	}
+	// Returned rather than exiting right away, so that the deferred cleanups, like writing the profile, still run.
+	if code := report.ExitCode(args.Init, c.Bool("fail-on-error")); code != ExitUnchanged {
+		return cli.NewExitError("", code)
+	}
+
+	return nil
+}
+
+func main() {
+	pathFlag := &cli.StringFlag{
+		Name:  "path",
+		Usage: "Path to the hashes.json file, default '~/tmp/doc_scraper_hashes.json'",
+	}
+	compactFlag := &cli.BoolFlag{
+		Name:  "compact",
+		Usage: "Write the hashes file as minified json. Regardless, a --path ending with .gz is gzipped",
+	}
+	concurrencyFlag := &cli.IntFlag{
+		Name:  "concurrency",
+		Usage: "Number of targets to fetch at the same time",
+		Value: 4,
+	}
+	perHostConcurrencyFlag := &cli.IntFlag{
+		Name:  "per-host-concurrency",
+		Usage: "Most requests in flight to any single host, on top of --concurrency; 0 for no limit",
+		Value: 2,
+	}
+	socks5Flag := &cli.StringFlag{
+		Name:  "socks5",
+		Usage: "'host:port' of a SOCKS5 proxy to fetch through, ex. '127.0.0.1:9050' for Tor. Hostnames are resolved by the proxy",
+	}
+	dnsServerFlag := &cli.StringFlag{
+		Name:  "dns-server",
+		Usage: "Resolve hostnames with this DNS server instead of the system's, ex. '1.1.1.1' or '1.1.1.1:53'",
+	}
+	maxRedirectsFlag := &cli.IntFlag{
+		Name:  "max-redirects",
+		Usage: "How many redirects to follow before failing the fetch, 0 to not follow any. Redirect loops fail right away",
+		Value: 10,
+	}
+	followMetaRefreshFlag := &cli.BoolFlag{
+		Name:  "follow-meta-refresh",
+		Usage: "Follow <meta http-equiv=\"refresh\"> redirects of pages, up to 5 in a row, rather than checking the stub page that does it",
+	}
+	insecureFlag := &cli.StringFlag{
+		Name:  "insecure-skip-verify",
+		Usage: "Comma-separated hosts to accept any TLS certificate of, ex. an expired one",
+	}
+	githubTokenFlag := &cli.StringFlag{
+		Name:   "github-token",
+		Usage:  "Token for the GitHub API, which github targets are fetched from, raising its rate limit from 60 to 5000 requests an hour. Can also be 'env:VAR' or 'file:/path/to/token'",
+		EnvVar: "GITHUB_TOKEN",
+	}
+	maxIdleConnsFlag := &cli.IntFlag{
+		Name:  "max-idle-conns",
+		Usage: "Most connections kept alive in between requests, across all hosts",
+		Value: 100,
+	}
+	maxConnsPerHostFlag := &cli.IntFlag{
+		Name:  "max-conns-per-host",
+		Usage: "Most connections open to a single host, also how many of them are kept alive; 0 for no limit",
+	}
+	disableHTTP2Flag := &cli.BoolFlag{
+		Name:  "disable-http2",
+		Usage: "Only use HTTP/1.1, for hosts that misbehave on HTTP/2",
+	}
+	urlFlag := &cli.StringFlag{
+		Name:  "url",
+		Usage: "Url of the targets to act on",
+	}
+	selectorFlag := &cli.StringFlag{
+		Name:  "selector",
+		Usage: "Only act on the target of --url with this selector, instead of all of them",
+	}
+	formatFlag := &cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format: 'table', 'json' or 'yaml'",
+		Value: "table",
+	}
+	selectorTypeFlag := &cli.StringFlag{
+		Name:  "selector-type",
+		Usage: "How to read selectors of targets that don't specify a selectorType: 'css' or 'xpath'",
+		Value: "css",
+	}
+	rawTextFlag := &cli.BoolFlag{
+		Name:  "raw-text",
+		Usage: "Hash the text exactly as in the html source, instead of the way a browser renders it",
+	}
+	runTimeoutFlag := &cli.DurationFlag{
+		Name:  "run-timeout",
+		Usage: "Hard limit on the whole run, ex. '10m'. When it runs out, whatever was checked is saved and the exit code is 3",
+	}
+	conditionalFlag := &cli.BoolFlag{
+		Name:  "conditional",
+		Usage: "Make requests conditional on the ETag, else the Last-Modified, of the last check, going by what each server offers and stopping to trust a validator that stays the same while the content changes",
+	}
+	historyLengthFlag := &cli.IntFlag{
+		Name:  "history-length",
+		Usage: "Keep the size and hash of the content as of this many recent checks of every target, for `history` to show its trend; 0 to keep none",
+	}
+	configFlag := &cli.StringFlag{
+		Name:  "config",
+		Usage: "Path to an optional config.json, defining named notifiers",
+	}
+	envFlag := &cli.StringFlag{
+		Name:  "env",
+		Usage: "Profile of the --config to use, ex. 'staging'. Its settings override the config's defaults",
+	}
+	notifiedFileFlag := &cli.StringFlag{
+		Name:  "notified-file",
+		Usage: "Keep the hash every target was last notified of in this file, and never notify of the same one twice, ex. when a run is retried after failing to save the hashes",
+	}
+	// Where notifications go, besides the notifiers of the --config.
+	notifierFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "telegram",
+			Usage: "Telegram bot token and chat ID to receive notification on; format: 'token,chatID', or 'token,chat1;chat2' to send to several chats. Ex: '123456:ABC-DEF1234ghIkl-zyx57W2,-1234567890'. The token can also be 'env:VAR' or 'file:/path/to/token'",
+		},
+		&cli.StringFlag{
+			Name:  "on-change",
+			Usage: "Shell command to run for every change, with DOC_URL, DOC_SELECTOR, DOC_OLD_HASH and DOC_NEW_HASH set in its environment",
+		},
+		&cli.DurationFlag{
+			Name:  "on-change-timeout",
+			Usage: "How long --on-change is allowed to run for",
+			Value: 30 * time.Second,
+		},
+		&cli.StringFlag{
+			Name:  "ntfy",
+			Usage: "ntfy topic url to publish changes to, ex. 'https://ntfy.sh/my-exchange-docs'",
+		},
+		&cli.StringFlag{
+			Name:  "ntfy-priority",
+			Usage: "Priority of the --ntfy messages: 1-5, or min, low, default, high, max/urgent",
+		},
+		&cli.StringFlag{
+			Name:  "ntfy-tags",
+			Usage: "Comma-separated tags of the --ntfy messages, ex. 'warning,books'",
+		},
+	}
+	// Flags that both check and init accept.
+	sharedFlags := []cli.Flag{
+		pathFlag,
+		compactFlag,
+		configFlag,
+		envFlag,
+		&cli.BoolFlag{
+			Name:  "quiet",
+			Usage: "Only print warnings and errors",
+		},
+		concurrencyFlag,
+		&cli.BoolFlag{
+			Name:  "concurrency-auto",
+			Usage: "Pick the concurrency from the number of CPUs and of distinct hosts, instead of --concurrency",
+		},
+		perHostConcurrencyFlag,
+		socks5Flag,
+		dnsServerFlag,
+		maxRedirectsFlag,
+		followMetaRefreshFlag,
+		insecureFlag,
+		githubTokenFlag,
+		maxIdleConnsFlag,
+		maxConnsPerHostFlag,
+		disableHTTP2Flag,
+		&cli.IntFlag{
+			Name:  "warn-threshold-chars",
+			Usage: "Warn about every target whose content is shorter than this many characters, ex. 50, which usually means a broken selector or an error page; 0 to not",
+		},
+		&cli.IntFlag{
+			Name:  "dns-retries",
+			Usage: "How many more times to try a target whose host failed to resolve, before reporting it as likely dead",
+			Value: 2,
+		},
+		runTimeoutFlag,
+		&cli.StringFlag{
+			Name:  "extractor",
+			Usage: "Command to extract the content of the pages with, instead of the selectors, for targets without their own 'extractor'. It gets the url and selector as arguments and the html on stdin, and prints the content to hash. 'readability' takes the main content of the pages, like a reader mode",
+		},
+		&cli.DurationFlag{
+			Name:  "extractor-timeout",
+			Usage: "How long --extractor is allowed to run for on a page",
+			Value: 30 * time.Second,
+		},
+		&cli.StringFlag{
+			Name:  "browser",
+			Usage: "Chromium-like browser to render the pages of screenshot targets with, ex. 'google-chrome'",
+			Value: "chromium",
+		},
+		&cli.DurationFlag{
+			Name:  "read-stall-timeout",
+			Usage: "Give up on a fetch once its response went this long without sending anything, ex. '30s', for servers that hold the connection open and trickle data; 0 for no limit",
+		},
+		selectorTypeFlag,
+		rawTextFlag,
+		&cli.BoolFlag{
+			Name:  "no-cache-buster",
+			Usage: "Fetch urls as they are, without the random nocache parameter that gets past caches, for servers that take it for another page or reject it",
+		},
+		&cli.StringFlag{
+			Name:  "accept-status",
+			Usage: "Comma-separated HTTP statuses to hash pages and feeds served with, ex. '200,203'; any other fails the fetch. Redirects are followed regardless",
+			Value: "200",
+		},
+		&cli.BoolFlag{
+			Name:  "merge-on-save",
+			Usage: "Re-read the hashes file before saving it, and only write over the targets this run changed, so that runs against the same file at the same time don't undo each other's updates",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-error",
+			Usage: "Exit with 2 if any target failed to be fetched or parsed, unless the run timed out",
+		},
+		&cli.BoolFlag{
+			Name:  "no-notify",
+			Usage: "Don't send any notifications this run; hashes are still updated and the exit code still reflects changes",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Check everything, but don't save hashes or snapshots, append events or send notifications",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run-diff",
+			Usage: "Same as --dry-run, and print the diff of every target that changed against its last snapshot. Needs --snapshot-dir",
+		},
+		&cli.BoolFlag{
+			Name:  "use-canonical",
+			Usage: "Move targets whose page declares another url as its canonical one over to that url, keeping their hashes",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "Write a pprof profile of the run, 'cpu' or 'mem', for tuning very large runs. See --profile-file",
+		},
+		&cli.StringFlag{
+			Name:  "profile-file",
+			Usage: "Where to write the --profile to, default 'doc_scraper.<cpu|mem>.pprof'",
+		},
+		&cli.StringFlag{
+			Name:  "print-config",
+			Usage: "Print the settings in effect, from the flags and the config file, as 'json' or 'yaml' and exit. Secrets are redacted",
+		},
+	}
+
+	app := cli.NewApp()
+	app.Name = "doc_scraper"
+	app.Usage = "Stupid little thing to catch exchange documentation changes."
+	app.Commands = []cli.Command{
+		{
+			Name:   "check",
+			Usage:  "Loads hashes and url:htmlClass from specified --path",
+			Action: runApplication,
+			Flags: append([]cli.Flag{
+				&cli.DurationFlag{
+					Name:  "min-run-gap",
+					Usage: "Refuse to check if the previous check of the same hashes file started less than this long ago, ex. 10m, against a cron running far more often than intended. Recorded in a .last-run file next to the hashes file",
+				},
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "Check even within --min-run-gap of the previous run",
+				},
+				historyLengthFlag,
+				notifiedFileFlag,
+				&cli.BoolFlag{
+					Name:  "notify-on-first-seen",
+					Usage: "Notify of the first content of new targets as 'now tracking', with its length, to confirm they're fetched fine, ex. after an import",
+				},
+				&cli.StringFlag{
+					Name:  "quiet-hours",
+					Usage: "Daily window to hold notifications back in, ex. '22:00-07:00'. They're queued next to the hashes file and sent by the first run after it; critical ones go out regardless",
+				},
+				&cli.StringFlag{
+					Name:  "timezone",
+					Usage: "Time zone of --quiet-hours, ex. 'Europe/Berlin'. The local one by default",
+				},
+				&cli.BoolFlag{
+					Name:  "quiet-hours-suppress",
+					Usage: "Drop the notifications of --quiet-hours instead of sending them once it's over",
+				},
+				&cli.IntFlag{
+					Name:  "fail-threshold",
+					Usage: "Report a target as dead once it fails this many checks in a row, 0 to never",
+					Value: 10,
+				},
+				&cli.DurationFlag{
+					Name:  "backoff",
+					Usage: "Don't check a target that failed again for this long, doubling with every further failure in a row, so dead hosts are tried less and less often; 0 to always retry",
+					Value: time.Hour,
+				},
+				&cli.DurationFlag{
+					Name:  "max-backoff",
+					Usage: "Longest a failing target is left alone for by --backoff",
+					Value: 7 * 24 * time.Hour,
+				},
+				&cli.StringFlag{
+					Name:  "snapshot-dir",
+					Usage: "Directory to keep the extracted content of every version of the targets in",
+				},
+				&cli.IntFlag{
+					Name:  "min-change-lines",
+					Usage: "Don't notify of changes touching fewer lines than this; their hash is still updated. Needs --snapshot-dir",
+				},
+				&cli.IntFlag{
+					Name:  "min-change-chars",
+					Usage: "Don't notify of changes touching fewer characters than this; their hash is still updated. Needs --snapshot-dir",
+				},
+				&cli.BoolFlag{
+					Name:  "head-first",
+					Usage: "Send a HEAD request first, and only download pages whose ETag, Last-Modified or Content-Length differ from the last check. For large pages that rarely change",
+				},
+				conditionalFlag,
+				&cli.IntFlag{
+					Name:  "verify-every",
+					Usage: "Download and hash the content anyway on every Nth check in a row that a 304 or --head-first said it didn't change, for servers with buggy ETags; 0 to always take their word",
+				},
+				&cli.Float64Flag{
+					Name:  "similarity-threshold",
+					Usage: "Don't notify of changes that leave the content at least this similar (0 to 1, ex. 0.9) to the last version notified of, going by a SimHash; 0 to notify of every change",
+				},
+				&cli.StringFlag{
+					Name:  "since-file",
+					Usage: "Keep what the run found changed in this file, and report which changes are new since the previous run that used it, even one with another hashes file",
+				},
+				&cli.StringFlag{
+					Name:  "changelog-file",
+					Usage: "Add a markdown section for the changes of the run to the top of this file, with their diffs if there are snapshots",
+				},
+				&cli.StringFlag{
+					Name:  "events-file",
+					Usage: "Append a json line per checked target to this file, or stream them to stdout as they happen with '-'",
+				},
+			}, append(notifierFlags, sharedFlags...)...),
 		},
 		{
 			Name:  "init",
@@ -200,16 +2013,300 @@ func main() {
 			Action: func(c *cli.Context) error {
 				return runApplication(c)
 			},
+			Flags: sharedFlags,
+		},
+		{
+			Name:   "disable",
+			Usage:  "Pauses checking the targets of --url, keeping their hashes",
+			Action: func(c *cli.Context) error { return setEnabled(c, false) },
+			Flags:  []cli.Flag{pathFlag, compactFlag, urlFlag, selectorFlag},
+		},
+		{
+			Name:   "enable",
+			Usage:  "Resumes checking the targets of --url",
+			Action: func(c *cli.Context) error { return setEnabled(c, true) },
+			Flags:  []cli.Flag{pathFlag, compactFlag, urlFlag, selectorFlag},
+		},
+		{
+			Name:   "ping",
+			Usage:  "Only fetches every target, reporting status code and latency, to check they're all reachable",
+			Action: pingTargets,
+			Flags:  []cli.Flag{pathFlag, concurrencyFlag, perHostConcurrencyFlag, socks5Flag, dnsServerFlag, maxRedirectsFlag, insecureFlag, maxIdleConnsFlag, maxConnsPerHostFlag, disableHTTP2Flag, formatFlag},
+		},
+		{
+			Name:   "verify-selectors",
+			Usage:  "Fetches every target and lists the selectors that match nothing anymore, or far more or fewer elements than on the last check, without updating anything",
+			Action: verifySelectors,
+			Flags:  []cli.Flag{pathFlag, concurrencyFlag, perHostConcurrencyFlag, socks5Flag, dnsServerFlag, maxRedirectsFlag, followMetaRefreshFlag, insecureFlag, selectorTypeFlag, formatFlag},
+		},
+		{
+			Name:   "list",
+			Usage:  "Lists the tracked targets and their state",
+			Action: listTargets,
+			Flags:  []cli.Flag{pathFlag, formatFlag},
+		},
+		{
+			Name:   "export",
+			Usage:  "Writes the tracked targets as csv to stdout, for reviewing them in a spreadsheet; edits go back in with import --force",
+			Action: exportTargets,
+			Flags: []cli.Flag{
+				pathFlag,
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "'csv' or 'tsv'",
+					Value: "csv",
+				},
+			},
+		},
+		{
+			Name:   "rename",
+			Usage:  "Rewrites the url of the targets matching --from-pattern, keeping their hashes",
+			Action: renameTargets,
+			Flags: []cli.Flag{
+				pathFlag,
+				compactFlag,
+				&cli.StringFlag{
+					Name:  "from-pattern",
+					Usage: "Regexp to match against the url of each target",
+				},
+				&cli.StringFlag{
+					Name:  "to-pattern",
+					Usage: "What to replace the match with; can refer to groups of --from-pattern, ex. '${1}'",
+				},
+				&cli.StringFlag{
+					Name:  "snapshot-dir",
+					Usage: "Snapshot directory to move the renamed targets' snapshots in",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only print what would be renamed",
+				},
+			},
+		},
+		{
+			Name:   "rekey",
+			Usage:  "Migrates the hashes file to be keyed by the targets' ids, with their url and selector as fields that can be edited in place, or back with --by url",
+			Action: rekeyTargets,
+			Flags: []cli.Flag{
+				pathFlag,
+				compactFlag,
+				&cli.StringFlag{
+					Name:  "by",
+					Value: "id",
+					Usage: "What to key the targets by: id or url",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only print how many targets would be rekeyed",
+				},
+			},
+		},
+		{
+			Name:   "prune",
+			Usage:  "Removes the targets without content, failing for long or no longer checked, after asking for confirmation",
+			Action: pruneTargets,
+			Flags: []cli.Flag{
+				pathFlag,
+				compactFlag,
+				&cli.BoolFlag{
+					Name:  "empty",
+					Usage: "Prune targets whose selector never matched anything",
+				},
+				&cli.IntFlag{
+					Name:  "min-failures",
+					Usage: "Prune targets that failed at least this many checks in a row",
+				},
+				&cli.DurationFlag{
+					Name:  "unchecked-for",
+					Usage: "Prune targets last checked longer ago than this, ex. '2160h' for 90 days",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only print what would be pruned",
+				},
+				&cli.BoolFlag{
+					Name:  "yes",
+					Usage: "Don't ask for confirmation",
+				},
+			},
+		},
+		{
+			Name:   "import",
+			Usage:  "Adds the targets listed in a url,selector csv (or tsv) --file, seeding their hashes",
+			Action: importTargets,
+			Flags: []cli.Flag{
+				pathFlag,
+				compactFlag,
+				&cli.StringFlag{
+					Name:  "file",
+					Usage: "Csv of url,selector rows to import; tab-separated if it ends with .tsv",
+				},
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "Re-seed the hashes of targets that are already tracked, instead of skipping them",
+				},
+				concurrencyFlag,
+				perHostConcurrencyFlag,
+				socks5Flag,
+				dnsServerFlag,
+				maxRedirectsFlag,
+				followMetaRefreshFlag,
+				insecureFlag,
+				githubTokenFlag,
+				selectorTypeFlag,
+				rawTextFlag,
+			},
+		},
+		{
+			Name:   "import-wayback",
+			Usage:  "Seeds the hash of --url and --selector from the Wayback Machine's snapshot at --date, so the next check reports what changed since then",
+			Action: importWayback,
+			Flags: []cli.Flag{
+				pathFlag,
+				compactFlag,
+				urlFlag,
+				selectorFlag,
+				&cli.StringFlag{
+					Name:  "date",
+					Usage: "When the snapshot should be from, as a prefix of YYYYMMDDhhmmss, ex. 20230115. The closest snapshot is used",
+				},
+				&cli.StringFlag{
+					Name:  "archive",
+					Usage: "Wayback Machine to get the snapshot from",
+					Value: "https://web.archive.org",
+				},
+				&cli.StringFlag{
+					Name:  "snapshot-dir",
+					Usage: "Also save the archived content in this snapshot directory, to diff the next check against",
+				},
+				socks5Flag,
+				dnsServerFlag,
+				maxRedirectsFlag,
+				followMetaRefreshFlag,
+				insecureFlag,
+				selectorTypeFlag,
+				rawTextFlag,
+			},
+		},
+		{
+			Name:   "compare",
+			Usage:  "Extracts --selector from --url-a and --url-b and prints whether they match, with a diff if they don't. Exits with 1 if they differ",
+			Action: compareURLs,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "url-a",
+					Usage: "Url to compare, ex. the production docs",
+				},
+				&cli.StringFlag{
+					Name:  "url-b",
+					Usage: "Url to compare it to, ex. the staging docs",
+				},
+				selectorFlag,
+				socks5Flag,
+				dnsServerFlag,
+				maxRedirectsFlag,
+				followMetaRefreshFlag,
+				insecureFlag,
+				githubTokenFlag,
+				selectorTypeFlag,
+				rawTextFlag,
+			},
+		},
+		{
+			Name:   "review",
+			Usage:  "Checks every target without saving, then steps through the changes to acknowledge them one by one",
+			Action: reviewChanges,
 			Flags: []cli.Flag{
+				pathFlag,
+				compactFlag,
+				concurrencyFlag,
+				perHostConcurrencyFlag,
+				socks5Flag,
+				dnsServerFlag,
+				maxRedirectsFlag,
+				followMetaRefreshFlag,
+				insecureFlag,
+				githubTokenFlag,
+				selectorTypeFlag,
+				rawTextFlag,
+				conditionalFlag,
+				historyLengthFlag,
+				runTimeoutFlag,
 				&cli.StringFlag{
-					Name:  "path",
-					Usage: "Path to the hashes.json file, default '~/tmp/doc_scraper_hashes.json'",
+					Name:  "snapshot-dir",
+					Usage: "Snapshot directory to diff the changes against; acknowledged ones are saved to it",
 				},
 			},
 		},
+		{
+			Name:   "replay",
+			Usage:  "Prints when each target changed, and by how much, from the snapshots in --snapshot-dir. Doesn't fetch anything",
+			Action: replaySnapshots,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "snapshot-dir",
+					Usage: "Snapshot directory to replay",
+				},
+				&cli.StringFlag{
+					Name:  "url",
+					Usage: "Only replay the targets of this url",
+				},
+				formatFlag,
+			},
+		},
+		{
+			Name:   "replay-notify",
+			Usage:  "Sends the notifications of the changes in --events-file from within --since again, through the notifiers configured now, ex. after an outage of Telegram. Doesn't fetch anything",
+			Action: replayNotify,
+			Flags: append([]cli.Flag{
+				pathFlag,
+				configFlag,
+				envFlag,
+				&cli.StringFlag{
+					Name:  "events-file",
+					Usage: "Events file that check appended the changes to",
+				},
+				&cli.DurationFlag{
+					Name:  "since",
+					Usage: "How far back to send changes again from, ex. 24h",
+				},
+				notifiedFileFlag,
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only print what would be sent again",
+				},
+			}, notifierFlags...),
+		},
+		{
+			Name:   "history",
+			Usage:  "Prints the recent sizes and hashes of the content of targets, as kept with --history-length. Doesn't fetch anything",
+			Action: showHistory,
+			Flags:  []cli.Flag{pathFlag, urlFlag, selectorFlag, formatFlag},
+		},
+		{
+			Name:   "groups",
+			Usage:  "Prints a single status and hash per group of targets, as of their last checks. Doesn't fetch anything",
+			Action: showGroups,
+			Flags: []cli.Flag{
+				pathFlag,
+				formatFlag,
+				&cli.BoolFlag{
+					Name:  "members",
+					Usage: "List the targets of every group, with their own status",
+				},
+			},
+		},
+		{
+			Name:   "stats",
+			Usage:  "Sums up the state of all tracked targets",
+			Action: showStats,
+			Flags:  []cli.Flag{pathFlag, formatFlag},
+		},
 	}
 
+	// Outcomes of a check come back as cli.ExitCoder, which exit with their own code within Run; whatever else is an error is a usage one.
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		os.Exit(ExitUsage)
 	}
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestJSONErrorPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "syntax error",
+			data: "{\n    \"a\": \"1\",\n    \"b\" \"2\"\n}",
+			want: ":3:9",
+		},
+		{
+			name: "type error",
+			data: "{\n    \"a\": {\"hash\": 5}\n}",
+			want: ":2:19",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v map[string]struct {
+				Hash string `json:"hash"`
+			}
+			err := json.Unmarshal([]byte(tt.data), &v)
+			if err == nil {
+				t.Fatal("expected the json to fail to parse")
+			}
+			if got := jsonErrorPosition([]byte(tt.data), err); got != tt.want {
+				t.Errorf("jsonErrorPosition = %q, want %q (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestJSONErrorPositionOtherErrors(t *testing.T) {
+	if got := jsonErrorPosition([]byte("{}"), errors.New("not a json error")); got != "" {
+		t.Errorf("jsonErrorPosition of an error without an offset = %q, want empty", got)
+	}
+}
+
+func TestNewTgArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []int64
+		wantErr bool
+	}{
+		{name: "single chat", input: "123:ABC,-1001", want: []int64{-1001}},
+		{name: "multiple chats", input: "123:ABC,-1001;42;7", want: []int64{-1001, 42, 7}},
+		{name: "whitespace", input: "123:ABC, -1001 ; 42 ", want: []int64{-1001, 42}},
+		{name: "empty items", input: "123:ABC,-1001;;42;", want: []int64{-1001, 42}},
+		{name: "no chats", input: "123:ABC, ; ", wantErr: true},
+		{name: "invalid chat", input: "123:ABC,-1001;team", wantErr: true},
+		{name: "no chat part", input: "123:ABC", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := NewTgArgs(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewTgArgs(%q) = %+v, want an error", tt.input, args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTgArgs(%q): %v", tt.input, err)
+			}
+			if args.BotToken != "123:ABC" {
+				t.Errorf("token = %q, want 123:ABC", args.BotToken)
+			}
+			if fmt.Sprint(args.ChatIds) != fmt.Sprint(tt.want) {
+				t.Errorf("chats = %v, want %v", args.ChatIds, tt.want)
+			}
+		})
+	}
+}
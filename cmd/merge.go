@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// StoreBase is every entry of the store as it was loaded, so that --merge-on-save can tell the ones the run changed from the ones it didn't touch.
+type StoreBase map[string][]byte
+
+func newStoreBase(hashes Hashes) (StoreBase, error) {
+	base := make(StoreBase, len(hashes))
+	for key, entry := range hashes {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", key, err)
+		}
+		base[key] = data
+	}
+	return base, nil
+}
+
+// changed says whether the entry of key differs from how it was loaded, a missing one included.
+func (b StoreBase) changed(key string, entry *Entry) bool {
+	loaded, ok := b[key]
+	if entry == nil {
+		return ok
+	}
+	data, err := json.Marshal(entry)
+	return !ok || err != nil || string(data) != string(loaded)
+}
+
+// mergeHashes merges the run's hashes into what's in the file by now, key by key: targets the run changed, added or removed are taken from ours,
+// and the rest from theirs, so that the updates of another run that saved in the meantime aren't lost.
+// Targets that both changed keep ours, and are returned, to be warned about.
+func mergeHashes(base StoreBase, ours, theirs Hashes) (Hashes, []string) {
+	merged := make(Hashes, len(theirs))
+	for key, entry := range theirs {
+		merged[key] = entry
+	}
+	var conflicts []string
+	keys := make(map[string]bool, len(base)+len(ours))
+	for key := range base {
+		keys[key] = true
+	}
+	for key := range ours {
+		keys[key] = true
+	}
+	for key := range keys {
+		entry := ours[key]
+		if !base.changed(key, entry) {
+			continue
+		}
+		if base.changed(key, theirs[key]) {
+			conflicts = append(conflicts, key)
+		}
+		if entry == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = entry
+		}
+	}
+	sort.Strings(conflicts)
+	return merged, conflicts
+}
+
+// saveMergedHashes is saveHashes for --merge-on-save: it re-reads the file right before writing it, and merges the run's changes into it.
+func saveMergedHashes(filePath string, base StoreBase, hashes Hashes, compact bool) error {
+	theirs, err := loadHashes(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		theirs = Hashes{}
+	} else if err != nil {
+		return fmt.Errorf("failed to re-read %s to merge into: %w", filePath, err)
+	}
+	merged, conflicts := mergeHashes(base, hashes, theirs)
+	for _, key := range conflicts {
+		fmt.Fprintf(os.Stderr, "%s was also updated by another run since this one started, keeping this run's\n", describeTarget(key, hashes[key]))
+	}
+	return saveHashes(filePath, merged, compact)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Two runs load the same store, another run saves first, and this one merges its changes into what it saved.
+func TestSaveMergedHashesConcurrentRuns(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "hashes.json")
+	keyA, keyB, keyC, keyD := joinKey("https://a.com", "h1"), joinKey("https://b.com", "h1"), joinKey("https://c.com", "h1"), joinKey("https://d.com", "h1")
+	if err := saveHashes(filePath, Hashes{keyA: {Hash: "a0"}, keyB: {Hash: "b0"}, keyD: {Hash: "d0"}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ours, err := loadHashes(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := newStoreBase(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The other run, between this one's load and save: updates b, adds c and both runs update d.
+	theirs, err := loadHashes(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs[keyB].Hash = "b1"
+	theirs[keyC] = &Entry{Hash: "c1"}
+	theirs[keyD].Hash = "d-theirs"
+	if err := saveHashes(filePath, theirs, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ours[keyA].Hash = "a1"
+	ours[keyD].Hash = "d-ours"
+	if err := saveMergedHashes(filePath, base, ours, false); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := loadHashes(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{keyA: "a1", keyB: "b1", keyC: "c1", keyD: "d-ours"}
+	if len(merged) != len(want) {
+		t.Errorf("merged %d targets, want %d", len(merged), len(want))
+	}
+	for key, hash := range want {
+		if entry, ok := merged[key]; !ok {
+			t.Errorf("%q was lost", key)
+		} else if entry.Hash != hash {
+			t.Errorf("hash of %q = %s, want %s", key, entry.Hash, hash)
+		}
+	}
+}
+
+func TestMergeHashesRemovals(t *testing.T) {
+	keyA, keyB := joinKey("https://a.com", "h1"), joinKey("https://b.com", "h1")
+	loaded := Hashes{keyA: {Hash: "a0"}, keyB: {Hash: "b0"}}
+	base, err := newStoreBase(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// This run removed a, the other one removed b.
+	ours := Hashes{keyB: {Hash: "b0"}}
+	theirs := Hashes{keyA: {Hash: "a0"}}
+	merged, conflicts := mergeHashes(base, ours, theirs)
+	if len(merged) != 0 {
+		t.Errorf("merged = %v, want both removals kept", merged)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts = %q, want none", conflicts)
+	}
+}
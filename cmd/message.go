@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of notifications whose message the config's `messages` can replace with a template.
+var messageEvents = []string{"changed", "first-seen", "new-items", "dead", "stale"}
+
+// MessageData is what a message template gets, ex. `{{.ID}} changed: {{.URL}}`. Fields that don't apply to the event are empty.
+type MessageData struct {
+	// One of messageEvents.
+	Event    string
+	ID       string
+	URL      string
+	Selector string
+	Group    string
+	OldHash  string
+	NewHash  string
+	// Kind and size of the change, ex. "modified, 3 lines, 120 characters", then the numbers that changed. Empty without --snapshot-dir to compare against.
+	DiffSummary string
+	// When the event happened, which is earlier than now for replay-notify.
+	Timestamp time.Time
+	// The new items of a feed, for new-items.
+	Items []FeedItem
+	// Checks failed in a row and the last error, for dead.
+	Failures int
+	Error    string
+	// How long the content hasn't changed for, for stale.
+	Since time.Duration
+	// The message as it would be without a template, to add to rather than replace.
+	Default string
+}
+
+// MessageTemplates are the parsed `messages` of the config, by event. A nil MessageTemplates leaves every message as it is.
+type MessageTemplates map[string]*template.Template
+
+func parseMessageTemplates(messages map[string]string) (MessageTemplates, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	events := make([]string, 0, len(messages))
+	for event := range messages {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	templates := make(MessageTemplates, len(messages))
+	for _, event := range events {
+		if !slices.Contains(messageEvents, event) {
+			return nil, fmt.Errorf("unknown event %q, expected one of: %s", event, strings.Join(messageEvents, ", "))
+		}
+		tmpl, err := template.New(event).Parse(messages[event])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", event, err)
+		}
+		templates[event] = tmpl
+	}
+	return templates, nil
+}
+
+// render is the message of the event: its template executed on the data, or the default message if it has none.
+// A template failing to execute, ex. referring to a field that doesn't exist, falls back to the default message rather than losing the notification.
+func (t MessageTemplates) render(data MessageData) string {
+	tmpl, ok := t[data.Event]
+	if !ok {
+		return data.Default
+	}
+	var message strings.Builder
+	if err := tmpl.Execute(&message, data); err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] Failed to execute the %s message template, sending the default message: %v\n", data.ID, data.Event, err)
+		return data.Default
+	}
+	return message.String()
+}
+
+// diffSummary is the DiffSummary of a change between two versions of the content.
+func diffSummary(change *ChangeRecord, previous, content string) string {
+	if change == nil {
+		return ""
+	}
+	size := measureDiff(previous, content)
+	summary := fmt.Sprintf("%s, %d lines, %d characters", change.Kind, size.Lines, size.Chars)
+	if numbers := change.summary(); numbers != "" {
+		summary += "\n" + numbers
+	}
+	return summary
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// NotifiedLog is what --notified-file keeps: the hash every target was last notified of, so that the same change isn't announced twice,
+// ex. by a retried run whose hashes didn't get saved. It's written on its own, before the hashes, so that failing to save those doesn't lose it.
+type NotifiedLog struct {
+	Path string
+
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+func loadNotifiedLog(filePath string) (*NotifiedLog, error) {
+	log := &NotifiedLog{Path: filePath, hashes: make(map[string]string)}
+	file, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(file, &log.hashes); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// Notified says whether the target was already notified of the content with that hash. Always false for a nil log.
+func (l *NotifiedLog) Notified(key, hash string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.hashes[key] == hash
+}
+
+func (l *NotifiedLog) Record(key, hash string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hashes[key] = hash
+}
+
+func (l *NotifiedLog) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := json.MarshalIndent(l.hashes, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.Path, file, 0644)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Valera6/doc_scraper/utils"
+)
+
+// Notifier is somewhere to send change messages to.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notification is a single change to tell about.
+type Notification struct {
+	// Human readable text, for the notifiers that just forward it.
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+	// See targetID.
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Selector string `json:"selector"`
+	OldHash  string `json:"oldHash,omitempty"`
+	NewHash  string `json:"newHash,omitempty"`
+	// What kind of change it is. nil without a snapshot to compare with.
+	Change *ChangeRecord `json:"change,omitempty"`
+	// The endpoints that changed, for OpenAPI and Swagger specs. nil for anything else.
+	Operations *OperationChanges `json:"operations,omitempty"`
+}
+
+type TelegramNotifier struct {
+	TgArgs TgArgs
+}
+
+func (n TelegramNotifier) Notify(ctx context.Context, notification Notification) error {
+	return utils.Msg(n.TgArgs.BotToken, n.TgArgs.ChatIds, notification.Message+"\n")
+}
+
+// SlackNotifier posts to a slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (n SlackNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(map[string]string{"text": notification.Message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// NtfyNotifier publishes to an ntfy topic, ex. https://ntfy.sh/my-docs.
+type NtfyNotifier struct {
+	TopicURL string
+	// 1-5, or one of min, low, default, high, max/urgent. Empty for the topic's default.
+	Priority string
+	// Comma-separated; ntfy shows the ones that match an emoji shortcode as emojis.
+	Tags string
+}
+
+var ntfyPriorities = map[string]bool{
+	"1": true, "2": true, "3": true, "4": true, "5": true,
+	"min": true, "low": true, "default": true, "high": true, "max": true, "urgent": true,
+}
+
+func (n NtfyNotifier) validate() error {
+	if u, err := url.Parse(n.TopicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return fmt.Errorf("expected a topic url like 'https://ntfy.sh/topic', got %q", n.TopicURL)
+	}
+	if n.Priority != "" && !ntfyPriorities[n.Priority] {
+		return fmt.Errorf("priority must be 1-5 or one of min, low, default, high, max, urgent, got %q", n.Priority)
+	}
+	return nil
+}
+
+func (n NtfyNotifier) Notify(ctx context.Context, notification Notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.TopicURL, strings.NewReader(notification.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", "doc_scraper: "+notification.ID)
+	if notification.URL != "" {
+		req.Header.Set("Click", notification.URL)
+	}
+	switch {
+	case n.Priority != "":
+		req.Header.Set("Priority", n.Priority)
+	case notification.Severity == Critical:
+		req.Header.Set("Priority", "urgent")
+	}
+	if n.Tags != "" {
+		req.Header.Set("Tags", n.Tags)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ntfy responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// CommandNotifier runs a shell command for every change, with the details of it in the environment:
+// DOC_MESSAGE, DOC_SEVERITY, DOC_ID, DOC_URL, DOC_SELECTOR, DOC_OLD_HASH and DOC_NEW_HASH, and with snapshots, DOC_CHANGE_KIND and DOC_NUMERIC_CHANGE. Whatever the command prints is relayed to Output.
+type CommandNotifier struct {
+	Command string
+	Timeout time.Duration
+	// nil for stdout.
+	Output io.Writer
+}
+
+func (n CommandNotifier) Notify(ctx context.Context, notification Notification) error {
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", n.Command)
+	cmd.Env = append(os.Environ(),
+		"DOC_MESSAGE="+notification.Message,
+		"DOC_SEVERITY="+notification.Severity.String(),
+		"DOC_ID="+notification.ID,
+		"DOC_URL="+notification.URL,
+		"DOC_SELECTOR="+notification.Selector,
+		"DOC_OLD_HASH="+notification.OldHash,
+		"DOC_NEW_HASH="+notification.NewHash,
+	)
+	if change := notification.Change; change != nil {
+		cmd.Env = append(cmd.Env, "DOC_CHANGE_KIND="+change.Kind, fmt.Sprintf("DOC_NUMERIC_CHANGE=%t", change.NumericChange))
+	}
+	output, err := cmd.CombinedOutput()
+	if n.Output != nil {
+		n.Output.Write(output)
+	} else {
+		os.Stdout.Write(output)
+	}
+	if err != nil {
+		return fmt.Errorf("command %q: %w", n.Command, err)
+	}
+	return nil
+}
+
+// Notifiers sends to every one of them, so that one failing doesn't stop the rest.
+type Notifiers []Notifier
+
+func (ns Notifiers) Notify(ctx context.Context, notification Notification) error {
+	var errs []error
+	for _, n := range ns {
+		if err := n.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifierConfig is a named notifier of the config file. Messages go to every backend that is set.
+type NotifierConfig struct {
+	// Same format as the --telegram flag: 'token,chatID', or 'token,chat1;chat2' for several chats.
+	Telegram string `json:"telegram,omitempty" yaml:"telegram,omitempty"`
+	// Slack incoming webhook url.
+	Slack string `json:"slack,omitempty" yaml:"slack,omitempty"`
+	// Shell command, same as --on-change.
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// ntfy topic url, with optional priority and comma-separated tags, same as the --ntfy flags.
+	Ntfy         string `json:"ntfy,omitempty" yaml:"ntfy,omitempty"`
+	NtfyPriority string `json:"ntfyPriority,omitempty" yaml:"ntfyPriority,omitempty"`
+	NtfyTags     string `json:"ntfyTags,omitempty" yaml:"ntfyTags,omitempty"`
+}
+
+// Notifier is the notifiers of the config. Commands relay what they print to output, nil for stdout.
+func (c NotifierConfig) Notifier(output io.Writer) (Notifier, error) {
+	var ns Notifiers
+	if c.Telegram != "" {
+		tgArgs, err := NewTgArgs(c.Telegram)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, TelegramNotifier{TgArgs: tgArgs})
+	}
+	if c.Slack != "" {
+		ns = append(ns, SlackNotifier{WebhookURL: c.Slack})
+	}
+	if c.Command != "" {
+		ns = append(ns, CommandNotifier{Command: c.Command, Timeout: 30 * time.Second, Output: output})
+	}
+	if c.Ntfy != "" {
+		ns = append(ns, NtfyNotifier{TopicURL: c.Ntfy, Priority: c.NtfyPriority, Tags: c.NtfyTags})
+	}
+	return ns, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// specOperations returns the hash of every operation of the OpenAPI or Swagger 2 spec, in json or yaml, by method and path, ex. "POST /v2/order",
+// so that a change can be told in terms of the endpoints it added, removed or modified. nil if the document isn't a spec.
+// An operation's hash covers its path's shared parameters too, but not the components it refers to.
+func specOperations(document string) map[string]string {
+	var version struct {
+		OpenAPI string `yaml:"openapi"`
+		Swagger string `yaml:"swagger"`
+	}
+	if err := yaml.Unmarshal([]byte(document), &version); err != nil {
+		return nil
+	}
+	var spec *openapi3.T
+	var err error
+	switch {
+	case version.OpenAPI != "":
+		spec, err = openapi3.NewLoader().LoadFromData([]byte(document))
+	case version.Swagger != "":
+		spec, err = swaggerToOpenAPI(document)
+	default:
+		return nil
+	}
+	if err != nil || spec.Paths == nil {
+		return nil
+	}
+	operations := make(map[string]string)
+	for path, item := range spec.Paths.Map() {
+		parameters, err := json.Marshal(item.Parameters)
+		if err != nil {
+			return nil
+		}
+		for method, operation := range item.Operations() {
+			encoded, err := json.Marshal(operation)
+			if err != nil {
+				return nil
+			}
+			operations[method+" "+path] = getSHA256Hash(string(encoded) + "\n" + string(parameters))
+		}
+	}
+	return operations
+}
+
+// swaggerToOpenAPI converts the Swagger 2 spec to OpenAPI 3, for its operations to be compared the same way.
+func swaggerToOpenAPI(document string) (*openapi3.T, error) {
+	converted, err := canonicalYAML([]byte(document))
+	if err != nil {
+		return nil, err
+	}
+	var swagger openapi2.T
+	if err := json.Unmarshal([]byte(converted), &swagger); err != nil {
+		return nil, err
+	}
+	return openapi2conv.ToV3(&swagger)
+}
+
+// OperationChanges is how the operations of a spec differ between two versions of it, each sorted.
+type OperationChanges struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// diffOperations compares the specOperations of two versions. Nothing if there is no previous version to compare against.
+func diffOperations(old, new map[string]string) OperationChanges {
+	var changes OperationChanges
+	if old == nil {
+		return changes
+	}
+	for operation, hash := range new {
+		switch oldHash, ok := old[operation]; {
+		case !ok:
+			changes.Added = append(changes.Added, operation)
+		case oldHash != hash:
+			changes.Modified = append(changes.Modified, operation)
+		}
+	}
+	for operation := range old {
+		if _, ok := new[operation]; !ok {
+			changes.Removed = append(changes.Removed, operation)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return changes
+}
+
+func (c OperationChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// String is ex. "added POST /v2/order, removed GET /v1/ticker".
+func (c OperationChanges) String() string {
+	var parts []string
+	for _, kind := range []struct {
+		verb       string
+		operations []string
+	}{{"added", c.Added}, {"removed", c.Removed}, {"modified", c.Modified}} {
+		for _, operation := range kind.operations {
+			parts = append(parts, fmt.Sprintf("%s %s", kind.verb, operation))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
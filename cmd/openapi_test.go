@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+const openAPIYAML = `openapi: 3.0.0
+info: {title: Exchange, version: "1"}
+paths:
+  /v1/ticker:
+    parameters:
+      - {name: symbol, in: query, schema: {type: string}}
+    get:
+      responses:
+        200: {description: The ticker}
+  /v2/order:
+    post:
+      responses:
+        "200": {description: The order}
+    delete:
+      responses:
+        "200": {description: Cancelled}
+`
+
+func TestSpecOperations(t *testing.T) {
+	openAPIJSON, err := canonicalYAML([]byte(openAPIYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{"openapi yaml", openAPIYAML, []string{"DELETE /v2/order", "GET /v1/ticker", "POST /v2/order"}},
+		{"openapi json", openAPIJSON, []string{"DELETE /v2/order", "GET /v1/ticker", "POST /v2/order"}},
+		{"swagger yaml", "swagger: '2.0'\ninfo: {title: Exchange, version: '1'}\npaths:\n  /v1/depth:\n    get:\n      responses:\n        200: {description: The depth}\n", []string{"GET /v1/depth"}},
+		{"not a spec", `{"paths": {"/v1/ticker": {"get": {}}}}`, nil},
+		{"not json nor yaml", "<html><body>Docs</body></html>", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations := specOperations(tt.document)
+			if (operations == nil) != (tt.want == nil) {
+				t.Fatalf("specOperations = %v, want %v", operations, tt.want)
+			}
+			var got []string
+			for operation := range operations {
+				got = append(got, operation)
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("operations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	// The same spec in either format, so nothing changes when a server switches between them.
+	fromYAML, fromJSON := specOperations(openAPIYAML), specOperations(openAPIJSON)
+	for operation, hash := range fromYAML {
+		if fromJSON[operation] != hash {
+			t.Errorf("hash of %s differs between the yaml and the json spec", operation)
+		}
+	}
+}
+
+func TestDiffOperations(t *testing.T) {
+	old := map[string]string{"GET /v1/ticker": "a", "GET /v1/depth": "b", "POST /v1/order": "c"}
+	tests := []struct {
+		name string
+		old  map[string]string
+		new  map[string]string
+		want OperationChanges
+	}{
+		{
+			name: "added",
+			old:  old,
+			new:  map[string]string{"GET /v1/ticker": "a", "GET /v1/depth": "b", "POST /v1/order": "c", "POST /v2/order": "d"},
+			want: OperationChanges{Added: []string{"POST /v2/order"}},
+		},
+		{
+			name: "removed",
+			old:  old,
+			new:  map[string]string{"GET /v1/depth": "b"},
+			want: OperationChanges{Removed: []string{"GET /v1/ticker", "POST /v1/order"}},
+		},
+		{
+			name: "modified",
+			old:  old,
+			new:  map[string]string{"GET /v1/ticker": "a", "GET /v1/depth": "b2", "POST /v1/order": "c"},
+			want: OperationChanges{Modified: []string{"GET /v1/depth"}},
+		},
+		{
+			name: "all at once",
+			old:  old,
+			new:  map[string]string{"GET /v1/ticker": "a2", "POST /v2/order": "d"},
+			want: OperationChanges{Added: []string{"POST /v2/order"}, Removed: []string{"GET /v1/depth", "POST /v1/order"}, Modified: []string{"GET /v1/ticker"}},
+		},
+		{name: "unchanged", old: old, new: old},
+		{name: "no previous version", old: nil, new: old},
+		// A page that stopped being a spec, or never was one, has no operations to tell apart.
+		{name: "not a spec", old: nil, new: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffOperations(tt.old, tt.new)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("diffOperations = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != (fmt.Sprint(tt.want) == fmt.Sprint(OperationChanges{})) {
+				t.Errorf("Empty() = %t for %+v", got.Empty(), got)
+			}
+		})
+	}
+}
+
+func TestOperationChangesString(t *testing.T) {
+	changes := OperationChanges{Added: []string{"POST /v2/order"}, Removed: []string{"GET /v1/ticker"}, Modified: []string{"GET /v1/depth"}}
+	if got, want := changes.String(), "added POST /v2/order, removed GET /v1/ticker, modified GET /v1/depth"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestSpecOperationsModified(t *testing.T) {
+	before := specOperations(openAPIYAML)
+	// The shared parameters of /v1/ticker change, along with the description of the order's deletion.
+	after := specOperations(`openapi: 3.0.0
+info: {title: Exchange, version: "1"}
+paths:
+  /v1/ticker:
+    parameters:
+      - {name: symbol, in: query, required: true, schema: {type: string}}
+    get:
+      responses:
+        200: {description: The ticker}
+  /v2/order:
+    post:
+      responses:
+        "200": {description: The order}
+    delete:
+      responses:
+        "200": {description: Canceled}
+`)
+	got := diffOperations(before, after)
+	want := OperationChanges{Modified: []string{"DELETE /v2/order", "GET /v1/ticker"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("diffOperations = %+v, want %+v", got, want)
+	}
+}
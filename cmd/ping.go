@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+)
+
+// PingResult is a row of `ping`.
+type PingResult struct {
+	URL     string        `json:"url" yaml:"url"`
+	Status  int           `json:"status" yaml:"status"`
+	Latency time.Duration `json:"latency" yaml:"latency"`
+	Error   string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// pingTargets fetches every page once, without hashing or comparing anything.
+func pingTargets(c *cli.Context) error {
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got: %d", concurrency)
+	}
+	args := RunArgs{
+		SOCKS5:          c.String("socks5"),
+		DNSServer:       c.String("dns-server"),
+		MaxRedirects:    c.Int("max-redirects"),
+		InsecureHosts:   insecureHosts(c),
+		MaxIdleConns:    c.Int("max-idle-conns"),
+		MaxConnsPerHost: c.Int("max-conns-per-host"),
+		DisableHTTP2:    c.Bool("disable-http2"),
+	}
+
+	pages := make(map[docKey]bool)
+	for key, entry := range hashes {
+		url, _, err := splitKey(key)
+		if err != nil {
+			return err
+		}
+		if entry.IsEnabled() {
+			pages[docKey{url: url, opts: args.fetchOptions(url, entry)}] = true
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		results []PingResult
+	)
+	hosts := NewHostLimiter(c.Int("per-host-concurrency"))
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for page := range pages {
+		g.Go(func() error {
+			result := PingResult{URL: page.url}
+			release, err := hosts.Acquire(context.Background(), page.url)
+			if err != nil {
+				result.Error = err.Error()
+				mu.Lock()
+				defer mu.Unlock()
+				results = append(results, result)
+				return nil
+			}
+			defer release()
+			start := time.Now()
+			resp, err := get(context.Background(), page.url, page.opts)
+			if err != nil {
+				result.Error = classifyTLS(err).Error()
+			} else {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				result.Status = resp.StatusCode
+			}
+			result.Latency = time.Since(start)
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, result)
+			return nil
+		})
+	}
+	g.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].URL < results[j].URL })
+
+	err = writeOutput(os.Stdout, c.String("format"), results, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "URL\tSTATUS\tLATENCY\tERROR")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", r.URL, r.Status, r.Latency.Round(time.Millisecond), r.Error)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	unreachable := 0
+	for _, r := range results {
+		if r.Status != http.StatusOK {
+			unreachable++
+		}
+	}
+	if unreachable > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d of %d pages didn't respond with 200", unreachable, len(results)), 1)
+	}
+	return nil
+}
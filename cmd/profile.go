@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfile starts a pprof profile of kind "cpu" or "mem", to be written to filePath (by default doc_scraper.<kind>.pprof) when the returned stop is called.
+// With an empty kind there's nothing to profile and stop does nothing.
+func startProfile(kind, filePath string) (stop func() error, err error) {
+	if kind == "" {
+		return func() error { return nil }, nil
+	}
+	if kind != "cpu" && kind != "mem" {
+		return nil, fmt.Errorf("--profile must be 'cpu' or 'mem', got: %s", kind)
+	}
+	if filePath == "" {
+		filePath = "doc_scraper." + kind + ".pprof"
+	}
+	filePath, err = expandHome(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "cpu" {
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		}, nil
+	}
+	return func() error {
+		// Up to date statistics of what's still allocated at the end of the run.
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(file); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, nil
+}
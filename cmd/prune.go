@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// pruneReason is why the target should be removed by `prune`, or nothing if it should be kept.
+func pruneReason(entry *Entry, empty bool, minFailures int, uncheckedFor time.Duration, now time.Time) string {
+	switch {
+	case empty && (entry.Hash == "" || entry.Hash == getSHA256Hash("")):
+		return "no content"
+	case minFailures > 0 && entry.ConsecutiveFailures >= minFailures:
+		return fmt.Sprintf("failed %d checks in a row", entry.ConsecutiveFailures)
+	case uncheckedFor > 0 && entry.LastChecked != nil && now.Sub(*entry.LastChecked) > uncheckedFor:
+		return fmt.Sprintf("not checked since %s", entry.LastChecked.Local().Format(time.DateTime))
+	default:
+		return ""
+	}
+}
+
+// pruneTargets removes the targets matching any of the criteria, after asking for confirmation, to keep a long-lived hashes file lean.
+// Their snapshots are left alone.
+func pruneTargets(c *cli.Context) error {
+	empty, minFailures, uncheckedFor := c.Bool("empty"), c.Int("min-failures"), c.Duration("unchecked-for")
+	if !empty && minFailures <= 0 && uncheckedFor <= 0 {
+		return fmt.Errorf("at least one of --empty, --min-failures or --unchecked-for is required")
+	}
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var pruned []string
+	for key, entry := range hashes {
+		if pruneReason(entry, empty, minFailures, uncheckedFor, now) != "" {
+			pruned = append(pruned, key)
+		}
+	}
+	sort.Strings(pruned)
+	for _, key := range pruned {
+		fmt.Printf("%s: %s\n", describeTarget(key, hashes[key]), pruneReason(hashes[key], empty, minFailures, uncheckedFor, now))
+	}
+	if len(pruned) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+	if c.Bool("dry-run") {
+		fmt.Printf("Would prune %d of %d targets\n", len(pruned), len(hashes))
+		return nil
+	}
+	if !c.Bool("yes") {
+		fmt.Printf("Prune %d of %d targets? [y/N] ", len(pruned), len(hashes))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Not pruning anything")
+			return nil
+		}
+	}
+	for _, key := range pruned {
+		delete(hashes, key)
+	}
+	if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+		return err
+	}
+	fmt.Printf("Pruned %d targets\n", len(pruned))
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuietHours is the daily window of --quiet-hours, ex. 22:00-07:00, during which notifications are held back.
+// Critical ones still go out right away, since they're about targets no longer being monitored at all.
+type QuietHours struct {
+	// Minutes since midnight. Start is after End when the window spans midnight.
+	Start, End int
+	Location   *time.Location
+	// Drop the notifications of the window, rather than queuing them until it's over.
+	Suppress bool
+	Queue    *NotificationQueue
+}
+
+func parseQuietHours(s string, location *time.Location) (*QuietHours, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("expected a window like '22:00-07:00', got %q", s)
+	}
+	q := &QuietHours{Location: location}
+	var err error
+	if q.Start, err = parseClock(start); err != nil {
+		return nil, err
+	}
+	if q.End, err = parseClock(end); err != nil {
+		return nil, err
+	}
+	if q.Start == q.End {
+		return nil, fmt.Errorf("window %q is empty", s)
+	}
+	return q, nil
+}
+
+// parseClock returns the minutes since midnight of "HH:MM".
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("expected a time like '07:00', got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains says whether t is within the window. Always false for a nil QuietHours.
+func (q *QuietHours) Contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	t = t.In(q.Location)
+	minute := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
+}
+
+// QueuedNotification is a notification held back by quiet hours, with the `notify` route of its target, to send it where it would have gone.
+type QueuedNotification struct {
+	Route        string       `json:"route,omitempty"`
+	Notification Notification `json:"notification"`
+	// Hash of the content of each target the notification is about, for the notified log to record once it's sent.
+	Notified map[string]string `json:"notified,omitempty"`
+}
+
+// NotificationQueue keeps the notifications held back by quiet hours in a file, until the first run after the window sends them.
+type NotificationQueue struct {
+	Path string
+
+	mu     sync.Mutex
+	queued []QueuedNotification
+}
+
+func loadNotificationQueue(filePath string) (*NotificationQueue, error) {
+	q := &NotificationQueue{Path: filePath}
+	file, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(file, &q.queued); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	return q, nil
+}
+
+func (q *NotificationQueue) Add(route string, notification Notification, notified map[string]string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queued = append(q.queued, QueuedNotification{Route: route, Notification: notification, Notified: notified})
+}
+
+// Save writes the queue back, removing the file once there's nothing left in it.
+func (q *NotificationQueue) Save() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queued) == 0 {
+		if err := os.Remove(q.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	file, err := json.MarshalIndent(q.queued, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.Path, file, 0644)
+}
+
+// Flush sends every queued notification where its target routes it, keeping the ones that fail to send for the next run.
+// Only the ones sent are recorded in the notified log, so that a change whose notification was dropped can still be notified of.
+func (q *NotificationQueue) Flush(ctx context.Context, args RunArgs) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queued) == 0 {
+		return
+	}
+	if !args.Quiet {
+		fmt.Fprintf(args.output(), "Sending %d notifications held back during quiet hours\n", len(q.queued))
+	}
+	var failed []QueuedNotification
+	for _, queued := range q.queued {
+		if _, ok := args.Routes[queued.Route]; queued.Route != "" && !ok {
+			fmt.Fprintf(os.Stderr, "Dropping the held back notification for %s, its notifier %q is no longer in the config\n", queued.Notification.URL, queued.Route)
+			continue
+		}
+		notifier := args.notifierFor(&Entry{Notify: queued.Route}, queued.Notification.Severity)
+		if notifier == nil {
+			continue
+		}
+		if err := notifier.Notify(ctx, queued.Notification); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send held back notification for %s: %v\n", queued.Notification.URL, err)
+			failed = append(failed, queued)
+			continue
+		}
+		for key, hash := range queued.Notified {
+			args.Notified.Record(key, hash)
+		}
+	}
+	q.queued = failed
+}
+
+// queueNotifier stands in for the notifier of a target during quiet hours, adding to the queue instead of sending.
+type queueNotifier struct {
+	queue *NotificationQueue
+	route string
+}
+
+func (n queueNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.queue.Add(n.route, notification, nil)
+	return nil
+}
+
+// deliver sends the notification of a change, and records the hashes of the content of the targets it's about in the notified log once it's sent.
+// During quiet hours that's when Flush sends it, not when it's queued.
+func deliver(ctx context.Context, notifier Notifier, notification Notification, notified map[string]string, args RunArgs) error {
+	if queue, ok := notifier.(queueNotifier); ok {
+		queue.queue.Add(queue.route, notification, notified)
+		return nil
+	}
+	if err := notifier.Notify(ctx, notification); err != nil {
+		return err
+	}
+	for key, hash := range notified {
+		args.Notified.Record(key, hash)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueuedNotificationsRecordedOnFlush(t *testing.T) {
+	dir := t.TempDir()
+	notified, err := loadNotifiedLog(filepath.Join(dir, "notified.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []Notification
+	args := RunArgs{Notifier: recordingNotifier{&sent}, Notified: notified, Quiet: true}
+	queue := &NotificationQueue{Path: filepath.Join(dir, "queued.json")}
+
+	ctx := context.Background()
+	if err := deliver(ctx, queueNotifier{queue: queue}, Notification{URL: "a"}, map[string]string{"a": "a1"}, args); err != nil {
+		t.Fatal(err)
+	}
+	// Routed to a notifier that's gone from the config by the time the queue is flushed.
+	if err := deliver(ctx, queueNotifier{queue: queue, route: "ops"}, Notification{URL: "b"}, map[string]string{"b": "b1"}, args); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 || notified.Notified("a", "a1") {
+		t.Fatalf("queued notifications were sent (%d) or recorded as notified during quiet hours", len(sent))
+	}
+
+	queue.Flush(ctx, args)
+	if len(sent) != 1 || sent[0].URL != "a" {
+		t.Fatalf("sent %+v, want a's notification only", sent)
+	}
+	if !notified.Notified("a", "a1") {
+		t.Error("a's notification was sent, but isn't recorded as notified")
+	}
+	if notified.Notified("b", "b1") {
+		t.Error("b's notification was dropped, but is recorded as notified")
+	}
+}
+
+func TestDeliverRecordsSentNotifications(t *testing.T) {
+	notified, err := loadNotifiedLog(filepath.Join(t.TempDir(), "notified.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []Notification
+	args := RunArgs{Notified: notified}
+	if err := deliver(context.Background(), recordingNotifier{&sent}, Notification{ID: "group"}, map[string]string{"a": "a1", "b": "b1"}, args); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !notified.Notified("a", "a1") || !notified.Notified("b", "b1") {
+		t.Errorf("sent %d notifications, recorded a: %t, b: %t; want 1 and both recorded", len(sent), notified.Notified("a", "a1"), notified.Notified("b", "b1"))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
+)
+
+// Extractor that takes the main content of the page, like a browser's reader mode does, instead of running a command.
+const readabilityExtractor = "readability"
+
+var errNoReadableContent = errors.New("found no main content on the page")
+
+// readableContent is the text of the main content of the page, as go-readability, a port of Mozilla's Readability, isolates it:
+// navigation, sidebars, footers, comments and lists of links are left out. It needs no selector, at the cost of being less precise than one.
+// The document is shared with the other targets of the page, and go-readability works on a copy of it.
+func readableContent(doc *goquery.Document, pageURL string, rawText bool) (string, error) {
+	// Only used to make the content's links absolute, which the text doesn't have.
+	parsed, _ := url.Parse(pageURL)
+	article, err := readability.FromDocument(doc.Nodes[0], parsed)
+	if err != nil {
+		return "", err
+	}
+	if article.Node == nil {
+		return "", errNoReadableContent
+	}
+	return nodesText([]*html.Node{article.Node}, rawText), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A docs page as exchanges lay them out: the article among a header, a menu, a table of contents and a footer.
+const docsPage = `<!DOCTYPE html>
+<html><head><title>Rate limits | Exchange API</title><script>window.analytics = {};</script></head>
+<body>
+  <header class="site-header"><a href="/">Exchange</a> <a href="/login">Log in</a> <a href="/signup">Sign up</a></header>
+  <nav class="sidebar-menu">
+    <ul><li><a href="/docs/intro">Introduction</a></li><li><a href="/docs/auth">Authentication</a></li><li><a href="/docs/limits">Rate limits</a></li></ul>
+  </nav>
+  <div class="doc-content">
+    <article>
+      <h1>Rate limits</h1>
+      <p>Every request counts towards the weight of the IP address it comes from, and orders also count towards the limits of the account that places them, per second, per minute and per day.</p>
+      <p>The REST API allows a weight of 6000 per minute per IP address. Most endpoints weigh 1, while the ones that return the order book, the trades or the klines of every symbol weigh more, up to 50.</p>
+      <p>Going over a limit gets a 429 response, with a Retry-After header saying how long to back off for. Ignoring it, and carrying on sending requests, bans the IP address for increasing durations, from 2 minutes to 3 days.</p>
+      <p>Order placement is limited to 50 orders per 10 seconds and 160000 orders per day per account, whatever the weight of the requests, and cancelling an order doesn't give back its share of the limit.</p>
+    </article>
+  </div>
+  <aside class="toc"><a href="#rest">REST</a> <a href="#orders">Orders</a> <a href="#bans">Bans</a></aside>
+  <footer class="site-footer"><p>© 2026 Exchange. <a href="/terms">Terms</a>, <a href="/privacy">Privacy</a>, <a href="/cookies">Cookies</a>.</p></footer>
+</body></html>`
+
+func TestReadableContent(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(docsPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := readableContent(doc, "https://example.com/docs/limits", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"weight of 6000 per minute", "Retry-After header", "50 orders per 10 seconds"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"Log in", "Authentication", "Bans", "Privacy", "analytics"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("content has %q from around the article:\n%s", unwanted, content)
+		}
+	}
+	// The other targets of the page select from the same document.
+	if doc.Find("nav.sidebar-menu").Length() != 1 || doc.Find("footer").Length() != 1 {
+		t.Error("the page's document was modified")
+	}
+}
+
+func TestReadableContentStable(t *testing.T) {
+	// Reindenting the page and changing what's around the article isn't a change of its content.
+	changed := strings.NewReplacer("\n  ", "\n    ", "Introduction", "Getting started", "© 2026", "© 2027").Replace(docsPage)
+	var contents []string
+	for _, page := range []string{docsPage, changed} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := readableContent(doc, "https://example.com/docs/limits", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, content)
+	}
+	if contents[0] != contents[1] {
+		t.Errorf("content changed along with the page around it:\n%s\nvs\n%s", contents[0], contents[1])
+	}
+}
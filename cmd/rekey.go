@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+// storeKey is the key in memory of the entry stored under key: the key itself in stores keyed by url and selector, or the url and selector
+// the entry has as attributes in stores keyed by id, whose id it then takes. Stores can mix both.
+func (e *Entry) storeKey(key string) (string, error) {
+	_, _, err := splitKey(key)
+	if err == nil || (e.URL == "" && e.Selector == "") {
+		return key, err
+	}
+	if e.URL == "" || e.Selector == "" {
+		return "", fmt.Errorf("target %q: keyed by id, so needs both a url and a selector", key)
+	}
+	if e.ID != "" && e.ID != key {
+		return "", fmt.Errorf("target %q: id: is the key in stores keyed by id, got %q", key, e.ID)
+	}
+	targetKey := joinKey(e.URL, e.Selector)
+	e.ID, e.URL, e.Selector, e.keyedByID = key, "", "", true
+	return targetKey, nil
+}
+
+// keyedByID says whether the hashes go to a store keyed by id, which is whenever any of them came from one.
+func (h Hashes) keyedByID() bool {
+	for _, entry := range h {
+		if entry.keyedByID {
+			return true
+		}
+	}
+	return false
+}
+
+// byID is the hashes as stored keyed by id: every target under its id, explicit or derived, with its url and selector as attributes.
+// Targets added since, ex. by import, get their derived id, which then sticks.
+func (h Hashes) byID() map[string]*Entry {
+	stored := make(map[string]*Entry, len(h))
+	for key, entry := range h {
+		copied := *entry
+		copied.URL, copied.Selector, _ = splitKey(key)
+		copied.ID = ""
+		stored[targetID(key, entry)] = &copied
+	}
+	return stored
+}
+
+// rekeyTargets migrates the hashes file to be keyed by the targets' ids rather than by their url and selector, or back with --by url.
+// Keyed by id, a target's url and selector can be edited in place, ex. after a docs reorganization, keeping its hash, state and snapshots.
+// Targets without an id keep the one derived from their url and selector, so their snapshots stay where they are.
+func rekeyTargets(c *cli.Context) error {
+	var byID bool
+	switch by := c.String("by"); by {
+	case "id":
+		byID = true
+	case "url":
+	default:
+		return fmt.Errorf("--by: expected id or url, got %q", by)
+	}
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	// Targets loaded from a store keyed by id have their id set explicitly, so it stays the same once keyed by url again.
+	for _, entry := range hashes {
+		entry.keyedByID = byID
+	}
+	if c.Bool("dry-run") {
+		fmt.Printf("Would key %d targets by %s\n", len(hashes), c.String("by"))
+		return nil
+	}
+	if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+		return err
+	}
+	fmt.Printf("Keyed %d targets by %s\n", len(hashes), c.String("by"))
+	return nil
+}
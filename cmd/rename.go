@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/urfave/cli"
+)
+
+// renameTargets rewrites the url part of the keys matching --from-pattern, keeping their hashes and state, so that a docs reorganization doesn't re-alert on every moved page.
+func renameTargets(c *cli.Context) error {
+	fromPattern, toPattern := c.String("from-pattern"), c.String("to-pattern")
+	if fromPattern == "" {
+		return fmt.Errorf("--from-pattern is required")
+	}
+	re, err := regexp.Compile(fromPattern)
+	if err != nil {
+		return fmt.Errorf("invalid --from-pattern: %w", err)
+	}
+	dryRun := c.Bool("dry-run")
+
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	var snapshots *SnapshotStore
+	if snapshotDir := c.String("snapshot-dir"); snapshotDir != "" {
+		snapshotDir, err = expandHome(snapshotDir)
+		if err != nil {
+			return err
+		}
+		snapshots = &SnapshotStore{Dir: snapshotDir, IDs: hashes.IDs()}
+	}
+
+	keys := make([]string, 0, len(hashes))
+	for key := range hashes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	renames := make(map[string]string)
+	for _, key := range keys {
+		url, htmlClass, err := splitKey(key)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(url) {
+			continue
+		}
+		newKey := joinKey(re.ReplaceAllString(url, toPattern), htmlClass)
+		if newKey == key {
+			continue
+		}
+		if _, ok := hashes[newKey]; ok {
+			return fmt.Errorf("renaming %q would overwrite the existing %q", key, newKey)
+		}
+		for from, to := range renames {
+			if to == newKey {
+				return fmt.Errorf("both %q and %q would be renamed to %q", from, key, newKey)
+			}
+		}
+		renames[key] = newKey
+		newURL, _, _ := splitKey(newKey)
+		fmt.Printf("%s -> %s (%s)\n", url, newURL, htmlClass)
+	}
+
+	if dryRun {
+		fmt.Printf("Would rename %d targets\n", len(renames))
+		return nil
+	}
+	for key, newKey := range renames {
+		hashes[newKey] = hashes[key]
+		delete(hashes, key)
+		if snapshots != nil {
+			if err := snapshots.Rename(key, newKey); err != nil {
+				return fmt.Errorf("failed to move the snapshots of %q: %w", key, err)
+			}
+		}
+	}
+	if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+		return err
+	}
+	fmt.Printf("Renamed %d targets\n", len(renames))
+	return nil
+}
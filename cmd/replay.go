@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// ReplayTimeline is the history of a single target, as reconstructed from its snapshots.
+type ReplayTimeline struct {
+	ID       string          `json:"id" yaml:"id"`
+	URL      string          `json:"url" yaml:"url"`
+	Selector string          `json:"selector" yaml:"selector"`
+	Versions []ReplayVersion `json:"versions" yaml:"versions"`
+}
+
+// ReplayVersion is a snapshot. Snapshots are only taken when the content differs from the previous one, so every version after the first is a change.
+type ReplayVersion struct {
+	Time  time.Time `json:"time" yaml:"time"`
+	Lines int       `json:"lines" yaml:"lines"`
+	// How big the change from the previous version is. nil for the first one.
+	Change *DiffSize `json:"change,omitempty" yaml:"change,omitempty"`
+}
+
+// replaySnapshots walks the snapshot directory and prints when each target changed, and by how much. Nothing is fetched.
+func replaySnapshots(c *cli.Context) error {
+	snapshotDir := c.String("snapshot-dir")
+	if snapshotDir == "" {
+		return fmt.Errorf("--snapshot-dir is required")
+	}
+	snapshotDir, err := expandHome(snapshotDir)
+	if err != nil {
+		return err
+	}
+	// Ids are taken from the directories, as the hashes file isn't read.
+	store := SnapshotStore{Dir: snapshotDir, IDs: make(map[string]string)}
+	keys, err := store.Targets()
+	if err != nil {
+		return err
+	}
+	onlyURL := c.String("url")
+
+	var timelines []ReplayTimeline
+	for _, key := range keys {
+		url, htmlClass, err := splitKey(key)
+		if err != nil {
+			return err
+		}
+		if onlyURL != "" && url != onlyURL {
+			continue
+		}
+		versions, err := store.Versions(key)
+		if err != nil {
+			return err
+		}
+		timeline := ReplayTimeline{ID: store.IDs[key], URL: url, Selector: htmlClass}
+		var previous string
+		for i, version := range versions {
+			at, err := VersionTime(version)
+			if err != nil {
+				return fmt.Errorf("unexpected snapshot file %s: %w", version, err)
+			}
+			content, err := os.ReadFile(version)
+			if err != nil {
+				return err
+			}
+			replayed := ReplayVersion{Time: at, Lines: len(splitLines(string(content)))}
+			if i > 0 {
+				change := measureDiff(previous, string(content))
+				replayed.Change = &change
+			}
+			timeline.Versions = append(timeline.Versions, replayed)
+			previous = string(content)
+		}
+		timelines = append(timelines, timeline)
+	}
+	if onlyURL != "" && len(timelines) == 0 {
+		return fmt.Errorf("no snapshots of %s in %s", onlyURL, snapshotDir)
+	}
+
+	return writeOutput(os.Stdout, c.String("format"), timelines, func(w *tabwriter.Writer) {
+		for _, timeline := range timelines {
+			fmt.Fprintf(w, "%s: %s (%s)\n", timeline.ID, timeline.URL, timeline.Selector)
+			for _, version := range timeline.Versions {
+				at := version.Time.Local().Format("2006-01-02 15:04:05")
+				if version.Change == nil {
+					fmt.Fprintf(w, "  %s\tfirst seen\t%d lines\n", at, version.Lines)
+				} else {
+					fmt.Fprintf(w, "  %s\tchanged\t%d lines, %d characters\n", at, version.Change.Lines, version.Change.Chars)
+				}
+			}
+		}
+	})
+}
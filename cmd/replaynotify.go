@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/Valera6/doc_scraper/pkg/checker"
+)
+
+// readChangeEvents returns the changes in the events file from after the given time, oldest first, leaving out the ones that weren't notified of.
+func readChangeEvents(filePath string, after time.Time) ([]Event, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filePath, line, err)
+		}
+		if event.Event == checker.Changed && !event.Minor && event.Timestamp.After(after) {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// replayNotify sends the notifications of the changes in the --events-file from within --since again, through the notifiers configured now,
+// to recover the ones lost while a notifier was down. Nothing is fetched. With --notified-file, the changes that did get notified of are skipped.
+func replayNotify(c *cli.Context) error {
+	since := c.Duration("since")
+	if since <= 0 {
+		return fmt.Errorf("--since is required, ex. 24h")
+	}
+	eventsPath := c.String("events-file")
+	if eventsPath == "" || eventsPath == "-" {
+		return fmt.Errorf("--events-file is required, the file that check appended its events to")
+	}
+	eventsPath, err := expandHome(eventsPath)
+	if err != nil {
+		return err
+	}
+	args := RunArgs{DryRun: c.Bool("dry-run")}
+	config, _, err := args.loadNotifiers(c)
+	if err != nil {
+		return err
+	}
+	if !args.DryRun && args.Notifier == nil && len(args.Routes) == 0 {
+		return fmt.Errorf("no notifiers to send to, pass ex. --telegram or a --config defining some")
+	}
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	if c.String("path") == "" && config.Path != "" {
+		filePath, err = expandHome(config.Path)
+		if err != nil {
+			return err
+		}
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	if notifiedPath := c.String("notified-file"); notifiedPath != "" {
+		notifiedPath, err = expandHome(notifiedPath)
+		if err != nil {
+			return err
+		}
+		args.Notified, err = loadNotifiedLog(notifiedPath)
+		if err != nil {
+			return fmt.Errorf("failed to read --notified-file: %w", err)
+		}
+	}
+	events, err := readChangeEvents(eventsPath, time.Now().Add(-since))
+	if err != nil {
+		return fmt.Errorf("failed to read --events-file: %w", err)
+	}
+
+	var sent, skipped, failed int
+	for _, event := range events {
+		key := joinKey(event.URL, event.Selector)
+		if args.Notified.Notified(key, event.NewHash) {
+			skipped++
+			continue
+		}
+		entry, ok := hashes[key]
+		if !ok {
+			// No longer tracked, so only the global notifiers are left.
+			entry = &Entry{}
+		}
+		at := event.Timestamp.Local().Format(time.DateTime)
+		if args.DryRun {
+			fmt.Printf("Would send again: [%s] changed at %s\n", event.ID, at)
+			sent++
+			continue
+		}
+		severity := changeSeverity(entry)
+		notifier := args.notifierFor(entry, severity)
+		if notifier == nil {
+			fmt.Fprintf(os.Stderr, "[%s] No notifier to send the change at %s to\n", event.ID, at)
+			failed++
+			continue
+		}
+		msg := fmt.Sprintf("[%s] Content changed for URL: %s (at %s, sent again)", event.ID, event.URL, at)
+		if event.ChangeRecord != nil && event.NumericChange {
+			msg += "\nNumbers changed:\n" + event.ChangeRecord.summary()
+		}
+		data := MessageData{
+			Event:     "changed",
+			ID:        event.ID,
+			URL:       event.URL,
+			Selector:  event.Selector,
+			Group:     entry.Group,
+			OldHash:   event.OldHash,
+			NewHash:   event.NewHash,
+			Timestamp: event.Timestamp,
+			Default:   msg,
+		}
+		notification := Notification{
+			Message:  args.Messages.render(data),
+			Severity: severity,
+			ID:       event.ID,
+			URL:      event.URL,
+			Selector: event.Selector,
+			OldHash:  event.OldHash,
+			NewHash:  event.NewHash,
+			Change:   event.ChangeRecord,
+		}
+		if err := notifier.Notify(context.Background(), notification); err != nil {
+			fmt.Fprintf(os.Stderr, "[%s] Failed to send the change at %s again: %v\n", event.ID, at, err)
+			failed++
+			continue
+		}
+		args.Notified.Record(key, event.NewHash)
+		sent++
+	}
+	if args.Notified != nil && !args.DryRun {
+		if err := args.Notified.Save(); err != nil {
+			return fmt.Errorf("failed to write --notified-file: %w", err)
+		}
+	}
+
+	if args.DryRun {
+		fmt.Printf("Would send %d notifications again, %d were already sent\n", sent, skipped)
+		return nil
+	}
+	fmt.Printf("Sent %d notifications again, %d were already sent\n", sent, skipped)
+	if failed > 0 {
+		return cli.NewExitError(fmt.Sprintf("failed to send %d notifications", failed), 1)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+)
+
+// reviewChange is a target whose content differs from its stored hash, waiting for a decision.
+type reviewChange struct {
+	key     string
+	diff    []DiffLine
+	newHash string
+	content string
+	// What else the fetch found out, for acknowledging the change to update the entry with, the way a check would have.
+	meta FetchMeta
+	// Whether there was a snapshot to diff against. Without one, diff is just the new content.
+	hasPrevious  bool
+	acknowledged bool
+}
+
+// findChanges fetches every enabled target and returns the ones whose content changed, without touching hashes.
+// Once ctx is done no new fetches are started, and the changes found so far are returned.
+func findChanges(ctx context.Context, hashes Hashes, args RunArgs, concurrency int) ([]*reviewChange, error) {
+	var (
+		mu      sync.Mutex
+		changes []*reviewChange
+		errs    []string
+	)
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for key, entry := range hashes {
+		if !entry.IsEnabled() {
+			continue
+		}
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			target, err := newTarget(key, entry)
+			if err != nil {
+				return err
+			}
+			contentBlock, meta, err := args.fetcherFor(target).Fetch(ctx, target)
+			if err != nil {
+				if ctx.Err() != nil {
+					// Cut off midway, which isn't the target's fault.
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err.Error())
+				return nil
+			}
+			newHash := getSHA256Hash(contentBlock)
+			if newHash == entry.Hash {
+				return nil
+			}
+			change := &reviewChange{
+				key:     key,
+				newHash: newHash,
+				content: contentBlock,
+				meta:    meta,
+			}
+			var previous string
+			if args.Snapshots != nil {
+				previous, change.hasPrevious, err = args.Snapshots.Latest(key)
+				if err != nil {
+					return err
+				}
+			}
+			change.diff = diffLines(previous, contentBlock)
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, change)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		fmt.Fprintf(os.Stderr, "Some targets were skipped:\n%s\n", strings.Join(errs, "\n"))
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].key < changes[j].key })
+	return changes, nil
+}
+
+// reviewModel steps through the changes one at a time. Nothing is written until the program exits.
+type reviewModel struct {
+	changes []*reviewChange
+	current int
+	// First diff line on screen.
+	scroll int
+	height int
+}
+
+func (m reviewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "a", "s", "n":
+			m.changes[m.current].acknowledged = msg.String() == "a"
+			m.current++
+			m.scroll = 0
+			if m.current == len(m.changes) {
+				return m, tea.Quit
+			}
+		case "p", "left":
+			if m.current > 0 {
+				m.current--
+				m.scroll = 0
+			}
+		case "down", "j":
+			if m.scroll < len(m.changes[m.current].diff)-1 {
+				m.scroll++
+			}
+		case "up", "k":
+			if m.scroll > 0 {
+				m.scroll--
+			}
+		case "pgdown", " ":
+			m.scroll = min(m.scroll+m.diffHeight(), max(len(m.changes[m.current].diff)-1, 0))
+		case "pgup":
+			m.scroll = max(m.scroll-m.diffHeight(), 0)
+		}
+	}
+	return m, nil
+}
+
+// diffHeight is how many diff lines fit between the header and the help line.
+func (m reviewModel) diffHeight() int {
+	return max(m.height-4, 1)
+}
+
+func (m reviewModel) View() string {
+	if m.current >= len(m.changes) {
+		return ""
+	}
+	change := m.changes[m.current]
+	url, htmlClass, _ := splitKey(change.key)
+
+	var b strings.Builder
+	state := "pending"
+	if change.acknowledged {
+		state = "acknowledged"
+	}
+	fmt.Fprintf(&b, "[%d/%d] %s (%s) - %s\n", m.current+1, len(m.changes), url, htmlClass, state)
+	if !change.hasPrevious {
+		b.WriteString("No snapshot to diff against, this is all of the new content:\n")
+	} else {
+		b.WriteString("\n")
+	}
+	end := min(m.scroll+m.diffHeight(), len(change.diff))
+	for _, line := range change.diff[m.scroll:end] {
+		switch line.Op {
+		case Insert:
+			fmt.Fprintf(&b, "\x1b[32m+ %s\x1b[0m\n", line.Text)
+		case Delete:
+			fmt.Fprintf(&b, "\x1b[31m- %s\x1b[0m\n", line.Text)
+		default:
+			fmt.Fprintf(&b, "  %s\n", line.Text)
+		}
+	}
+	for i := end - m.scroll; i < m.diffHeight(); i++ {
+		b.WriteString("\n")
+	}
+	b.WriteString("a: acknowledge  s: leave flagged  p: previous  ↑/↓/space: scroll  q: quit, keeping decisions so far")
+	return b.String()
+}
+
+// reviewChanges checks every target without saving, then lets the user decide which changes to acknowledge.
+// Only acknowledged targets get their hash updated; the rest keep showing up as changed.
+func reviewChanges(c *cli.Context) error {
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	// Saved the way --merge-on-save does, as a check may well run against the same file while the review waits on the user.
+	base, err := newStoreBase(hashes)
+	if err != nil {
+		return err
+	}
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got: %d", concurrency)
+	}
+	githubToken, err := resolveSecret(c.String("github-token"))
+	if err != nil {
+		return fmt.Errorf("--github-token: %w", err)
+	}
+	args := RunArgs{
+		SelectorType:      c.String("selector-type"),
+		RawText:           c.Bool("raw-text"),
+		SOCKS5:            c.String("socks5"),
+		DNSServer:         c.String("dns-server"),
+		MaxRedirects:      c.Int("max-redirects"),
+		FollowMetaRefresh: c.Bool("follow-meta-refresh"),
+		InsecureHosts:     insecureHosts(c),
+		GitHubToken:       githubToken,
+		Conditional:       c.Bool("conditional"),
+		HistoryLength:     c.Int("history-length"),
+		Docs:              NewDocCache(0, c.Int("per-host-concurrency")),
+	}
+	if args.SelectorType != "css" && args.SelectorType != "xpath" {
+		return fmt.Errorf("selector type must be 'css' or 'xpath', got: %s", args.SelectorType)
+	}
+	if snapshotDir := c.String("snapshot-dir"); snapshotDir != "" {
+		snapshotDir, err = expandHome(snapshotDir)
+		if err != nil {
+			return err
+		}
+		args.Snapshots = &SnapshotStore{Dir: snapshotDir, IDs: hashes.IDs()}
+	}
+
+	// Ctrl-C stops the fetches without acknowledging anything. Once the review is on screen, it gets the Ctrl-C itself.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if runTimeout := c.Duration("run-timeout"); runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
+	changes, err := findChanges(ctx, hashes, args, concurrency)
+	if err != nil {
+		return err
+	}
+	switch ctx.Err() {
+	case context.Canceled:
+		return fmt.Errorf("interrupted, nothing acknowledged")
+	case context.DeadlineExceeded:
+		fmt.Fprintf(os.Stderr, "Timed out after %s, reviewing the changes found so far\n", c.Duration("run-timeout"))
+	}
+	stop()
+	if len(changes) == 0 {
+		fmt.Println("Nothing changed")
+		return nil
+	}
+	if _, err := tea.NewProgram(reviewModel{changes: changes}, tea.WithAltScreen()).Run(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	acknowledged := 0
+	for _, change := range changes {
+		if !change.acknowledged {
+			continue
+		}
+		acknowledged++
+		entry := hashes[change.key]
+		target, err := newTarget(change.key, entry)
+		if err != nil {
+			return err
+		}
+		// The way writeChanges leaves the entry of a change it notified of.
+		args.recordFetch(entry, target, change.content, change.meta, false, now)
+		entry.Hash = change.newHash
+		entry.LastChanged = &now
+		entry.StaleNotified = false
+		entry.ConsecutiveFailures = 0
+		entry.NextCheck = nil
+		if args.similarityThreshold(entry) > 0 {
+			newSimHash := simHash(change.content)
+			if change.meta.Screenshot != nil {
+				newSimHash = change.meta.VisualHash
+			}
+			entry.SimHash = formatSimHash(newSimHash)
+		}
+		if args.Snapshots != nil {
+			if err := args.Snapshots.Save(change.key, change.content, now); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save a snapshot of %s: %v\n", change.key, err)
+			}
+		}
+	}
+	if acknowledged > 0 {
+		if err := saveMergedHashes(filePath, base, hashes, c.Bool("compact")); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Acknowledged %d of %d changes\n", acknowledged, len(changes))
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// lastRunPath is where checks with --min-run-gap record when they started, next to the hashes file they're of.
+func lastRunPath(filePath string) string {
+	return filePath + ".last-run"
+}
+
+// loadLastRun returns when the previous check recorded in filePath started, and the zero time if none did.
+func loadLastRun(filePath string) (time.Time, error) {
+	file, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(file)))
+}
+
+func saveLastRun(filePath string, at time.Time) error {
+	return os.WriteFile(filePath, []byte(at.Format(time.RFC3339Nano)+"\n"), 0644)
+}
+
+// checkRunGap refuses a run that starts less than gap after the previous one, saying how long to wait, so that a cron scheduled too often
+// can't get the hosts to ban us. Otherwise it records the run, from its start so that overlapping runs count too, unless told not to write anything.
+func checkRunGap(filePath string, gap time.Duration, force, dryRun bool, now time.Time) error {
+	lastRun, err := loadLastRun(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read the time of the previous run: %w", err)
+	}
+	if wait := lastRun.Add(gap).Sub(now); !force && !lastRun.IsZero() && wait > 0 {
+		return fmt.Errorf("the previous run started at %s, less than --min-run-gap %s ago; wait %s, or pass --force to run anyway",
+			lastRun.Local().Format(time.DateTime), gap, wait.Round(time.Second))
+	}
+	if dryRun {
+		return nil
+	}
+	return saveLastRun(filePath, now)
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/robfig/cron/v3"
+)
+
+// parseSchedule parses a target's schedule: a standard 5 field cron expression (minute, hour, day of month, month and day of week),
+// like "*/15 9-17 * * MON-FRI", or a descriptor like "@hourly". When both the day of month and of week are restricted, matching either is enough.
+// The schedule's Next is the zero time when it never fires again within 5 years, ex. "0 0 30 2 *".
+func parseSchedule(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
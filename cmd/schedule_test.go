@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2026, time.January, 28, 10, 20, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"hourly", "0 * * * *", time.Date(2026, time.January, 28, 11, 0, 0, 0, time.UTC)},
+		{"descriptor", "@hourly", time.Date(2026, time.January, 28, 11, 0, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", time.Date(2026, time.January, 28, 10, 30, 0, 0, time.UTC)},
+		{"range", "0 9-17 * * *", time.Date(2026, time.January, 28, 11, 0, 0, 0, time.UTC)},
+		{"past the range", "0 1-3 * * *", time.Date(2026, time.January, 29, 1, 0, 0, 0, time.UTC)},
+		{"weekday names", "0 9 * * SAT,SUN", time.Date(2026, time.January, 31, 9, 0, 0, 0, time.UTC)},
+		{"month rollover", "0 0 1 * *", time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"year rollover", "0 0 1 JAN *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// Either the 1st of the month or a Friday, whichever comes first.
+		{"day of month or of week", "0 0 1 * FRI", time.Date(2026, time.January, 30, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseSchedule(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) of %q = %s, want %s", from, tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "0 * * *", "60 * * * *", "0 0 * * MON-FUN", "@fortnightly"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestWriteChangesSkipsTargetsNotDue(t *testing.T) {
+	lastChecked := time.Now()
+	key := joinKey("https://example.com/docs", "h1")
+	hashes := Hashes{key: {Hash: "a", Schedule: "0 0 1 1 *", LastChecked: &lastChecked}}
+	status, _, err := writeChanges(context.Background(), hashes, key, RunArgs{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != Skipped {
+		t.Errorf("status = %v, want the target skipped until next year", status)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ScreenshotFetcher renders the page in a headless browser, for pages whose layout matters and whose text doesn't show it.
+// The content is a perceptual hash of the screenshot, which the similarity threshold compares instead of a simHash of the text,
+// so that antialiasing and the like don't count as changes. The selector isn't used.
+//
+// It's slow and needs chromium (or --browser) installed, so only targets that ask for it with `fetcher: "screenshot"` use it.
+type ScreenshotFetcher struct {
+	Args RunArgs
+}
+
+// Size of the browser window, which is also what's captured.
+const screenshotWindow = "1280,2000"
+
+// Screenshots are at most this similar to the last notified one before they count as a change, when no threshold is set.
+const defaultScreenshotSimilarity = 0.9
+
+func (f ScreenshotFetcher) Fetch(ctx context.Context, target Target) (string, FetchMeta, error) {
+	args, url := f.Args, target.URL
+	var meta FetchMeta
+	release, err := args.Docs.Hosts.Acquire(ctx, url)
+	if err != nil {
+		return "", meta, err
+	}
+	defer release()
+
+	dir, err := os.MkdirTemp("", "doc_scraper_screenshot")
+	if err != nil {
+		return "", meta, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "screenshot.png")
+	browser := args.Browser
+	if browser == "" {
+		browser = "chromium"
+	}
+	cmd := exec.CommandContext(ctx, browser, "--headless", "--disable-gpu", "--hide-scrollbars", "--window-size="+screenshotWindow, "--screenshot="+file, url)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", meta, fmt.Errorf("failed to take a screenshot of %s with %s: %w: %s", url, browser, err, strings.TrimSpace(string(output)))
+	}
+	screenshot, err := os.ReadFile(file)
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to take a screenshot of %s with %s: %w", url, browser, err)
+	}
+	img, err := png.Decode(bytes.NewReader(screenshot))
+	if err != nil {
+		return "", meta, fmt.Errorf("failed to read the screenshot of %s: %w", url, err)
+	}
+	meta.Screenshot = screenshot
+	meta.VisualHash = perceptualHash(img)
+	return formatSimHash(meta.VisualHash), meta, nil
+}
+
+// perceptualHash is the difference hash of the image: it's shrunk to 9x8 gray pixels, and every bit says whether a pixel is brighter than the one on its right.
+// Images that look alike get hashes that differ in few bits, regardless of their size or of small rendering differences.
+func perceptualHash(img image.Image) uint64 {
+	const width, height = 9, 8
+	bounds := img.Bounds()
+	var gray [height][width]float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Average of the block of pixels the cell covers.
+			x0, x1 := bounds.Min.X+x*bounds.Dx()/width, bounds.Min.X+(x+1)*bounds.Dx()/width
+			y0, y1 := bounds.Min.Y+y*bounds.Dy()/height, bounds.Min.Y+(y+1)*bounds.Dy()/height
+			var sum float64
+			var n int
+			for py := y0; py < max(y1, y0+1); py++ {
+				for px := x0; px < max(x1, x0+1); px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					n++
+				}
+			}
+			gray[y][x] = sum / float64(n)
+		}
+	}
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1 << (y*(width-1) + x)
+			}
+		}
+	}
+	return hash
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// page draws a screenshot-like image of the given size: bands of shades that run diagonally across it, the same at any size.
+func page(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u, v := float64(x)/float64(width), float64(y)/float64(height)
+			img.SetGray(x, y, color.Gray{Y: uint8(128 + 100*math.Sin(3*math.Pi*u+2*math.Pi*v))})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	base := perceptualHash(page(1280, 2000))
+
+	// A banner showing up over a corner of the page.
+	banner := page(1280, 2000)
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			banner.SetGray(x, y, color.Gray{Y: 40})
+		}
+	}
+	// The page turned around, as with a redesign.
+	mirrored := page(1280, 2000)
+	for y := 0; y < 2000; y++ {
+		for x := 0; x < 640; x++ {
+			left, right := mirrored.GrayAt(x, y), mirrored.GrayAt(1279-x, y)
+			mirrored.SetGray(x, y, right)
+			mirrored.SetGray(1279-x, y, left)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		img     image.Image
+		similar bool
+	}{
+		{"same page", page(1280, 2000), true},
+		{"different size", page(800, 1250), true},
+		{"small change", banner, true},
+		{"different layout", mirrored, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similarity := simHashSimilarity(base, perceptualHash(tt.img))
+			if (similarity >= defaultScreenshotSimilarity) != tt.similar {
+				t.Errorf("similarity = %.2f, want similar: %t", similarity, tt.similar)
+			}
+		})
+	}
+	if got := perceptualHash(page(1280, 2000)); got != base {
+		t.Errorf("hash of the same page = %016x, then %016x", base, got)
+	}
+}
+
+func TestPerceptualHashBits(t *testing.T) {
+	// Every pixel brighter than the one on its right sets its bit, and none do the other way around.
+	gradient := func(brighterLeft bool) image.Image {
+		img := image.NewGray(image.Rect(0, 0, 90, 80))
+		for y := 0; y < 80; y++ {
+			for x := 0; x < 90; x++ {
+				shade := uint8(x * 2)
+				if brighterLeft {
+					shade = 255 - shade
+				}
+				img.SetGray(x, y, color.Gray{Y: shade})
+			}
+		}
+		return img
+	}
+	if got := perceptualHash(gradient(true)); got != math.MaxUint64 {
+		t.Errorf("hash of a left to right fade = %016x, want every bit set", got)
+	}
+	if got := perceptualHash(gradient(false)); got != 0 {
+		t.Errorf("hash of a right to left fade = %016x, want none set", got)
+	}
+}
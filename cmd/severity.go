@@ -0,0 +1,64 @@
+package main
+
+import "fmt"
+
+// Severity is how urgent a notification is, so that the config can route each to a different notifier.
+type Severity int
+
+const (
+	// Changes of the content and new feed items, unless their target says otherwise.
+	Info Severity = iota
+	// Stale content.
+	Warn
+	// Targets that appear dead, as it means they're no longer monitored at all.
+	Critical
+)
+
+var severityNames = []string{"info", "warn", "critical"}
+
+func parseSeverity(s string) (Severity, error) {
+	for i, name := range severityNames {
+		if s == name {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("must be one of info, warn or critical, got %q", s)
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := parseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// severityNotifier is where notifications of that severity go on top of the usual notifier: the route of the config's severityRoutes
+// for the highest severity up to it. So with only "info" and "critical" routes, warnings go with the info ones.
+func (args RunArgs) severityNotifier(severity Severity) Notifier {
+	for s := severity; s >= Info; s-- {
+		if notifier, ok := args.SeverityRoutes[s]; ok {
+			return notifier
+		}
+	}
+	return nil
+}
+
+// changeSeverity is the severity of a change of the target's content.
+func changeSeverity(entry *Entry) Severity {
+	if entry.Severity == "" {
+		return Info
+	}
+	// Already validated on load.
+	severity, _ := parseSeverity(entry.Severity)
+	return severity
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// simHash is a 64 bit locality-sensitive hash of text: similar texts get hashes that differ in few bits.
+// Features are pairs of consecutive words, so that both the words and their order count.
+func simHash(text string) uint64 {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 1 {
+		words = append(words, "")
+	}
+	var weights [64]int
+	for i := 0; i+1 < len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(words[i] + " " + words[i+1]))
+		feature := h.Sum64()
+		for bit := range weights {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+func formatSimHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func parseSimHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// simHashSimilarity is the part of the bits the two hashes have in common, from 0 to 1.
+func simHashSimilarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
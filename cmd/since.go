@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"time"
+)
+
+// RunMarker is what --since-file keeps about the previous run: when it was, and what it found changed, with the hash it changed to.
+// Keeping the hashes lets runs against separate hashes files agree on what is new, since the same change has the same hash everywhere.
+type RunMarker struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Changed   map[string]string `json:"changed"`
+}
+
+// loadRunMarker returns the marker in filePath, and false if there's none yet.
+func loadRunMarker(filePath string) (RunMarker, bool, error) {
+	var marker RunMarker
+	file, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return marker, false, nil
+	}
+	if err != nil {
+		return marker, false, err
+	}
+	if err := json.Unmarshal(file, &marker); err != nil {
+		return marker, false, err
+	}
+	return marker, true, nil
+}
+
+func saveRunMarker(filePath string, marker RunMarker) error {
+	file, err := json.MarshalIndent(marker, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, file, 0644)
+}
+
+// newSince returns the changed keys that the marker doesn't already know about with the same hash, sorted.
+func (m RunMarker) newSince(changed []string, hashes Hashes) []string {
+	var fresh []string
+	for _, key := range changed {
+		if m.Changed[key] != hashes[key].Hash {
+			fresh = append(fresh, key)
+		}
+	}
+	sort.Strings(fresh)
+	return fresh
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotStore keeps the extracted content of every version of each target, so there's something to diff new content against.
+// Layout is <dir>/<id>/<timestamp>.txt, with <dir>/<id>/target holding the key it belongs to.
+type SnapshotStore struct {
+	Dir string
+	// Explicit ids of the targets, by key, see Hashes.IDs. Targets that aren't in there go by their slugID.
+	IDs map[string]string
+}
+
+const snapshotTimeFormat = "20060102T150405.000000000Z"
+
+func (s SnapshotStore) targetDir(key string) string {
+	id := s.IDs[key]
+	if id == "" {
+		id = slugID(key)
+	}
+	dir := filepath.Join(s.Dir, id)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return dir
+	}
+	// Snapshots taken before the target had an id set, or before there were ids at all, are moved over on first use.
+	for _, previous := range []string{slugID(key), getSHA256Hash(key)[:16]} {
+		if previous != id && os.Rename(filepath.Join(s.Dir, previous), dir) == nil {
+			break
+		}
+	}
+	return dir
+}
+
+// Latest returns the most recently saved content of the target, and false if there is none.
+func (s SnapshotStore) Latest(key string) (string, bool, error) {
+	versions, err := s.Versions(key)
+	if err != nil || len(versions) == 0 {
+		return "", false, err
+	}
+	content, err := os.ReadFile(versions[len(versions)-1])
+	if err != nil {
+		return "", false, err
+	}
+	return string(content), true, nil
+}
+
+// Targets returns the keys of every target that has snapshots, sorted. With IDs set, it also remembers which directory each is in.
+func (s SnapshotStore) Targets() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*", "target"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		key, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, string(key))
+		if s.IDs != nil {
+			s.IDs[string(key)] = filepath.Base(filepath.Dir(file))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// VersionTime is when the snapshot file of a version was taken, from its name.
+func VersionTime(version string) (time.Time, error) {
+	return time.Parse(snapshotTimeFormat, strings.TrimSuffix(filepath.Base(version), ".txt"))
+}
+
+// Versions lists the target's snapshot files, oldest first.
+func (s SnapshotStore) Versions(key string) ([]string, error) {
+	versions, err := filepath.Glob(filepath.Join(s.targetDir(key), "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+func (s SnapshotStore) Save(key, content string, at time.Time) error {
+	dir := s.targetDir(key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "target"), []byte(key), 0644); err != nil {
+		return err
+	}
+	name := at.UTC().Format(snapshotTimeFormat) + ".txt"
+	return os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+}
+
+// SaveImage keeps a screenshot along with the snapshot taken at the same time.
+func (s SnapshotStore) SaveImage(key string, image []byte, at time.Time) error {
+	dir := s.targetDir(key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, at.UTC().Format(snapshotTimeFormat)+".png"), image, 0644)
+}
+
+// Rename moves the target's snapshots over to its new key, if it has any.
+func (s SnapshotStore) Rename(oldKey, newKey string) error {
+	if id, ok := s.IDs[oldKey]; ok {
+		s.IDs[newKey] = id
+	}
+	oldDir, newDir := s.targetDir(oldKey), s.targetDir(newKey)
+	if _, err := os.Stat(oldDir); os.IsNotExist(err) {
+		return nil
+	}
+	if oldDir != newDir {
+		if err := os.Rename(oldDir, newDir); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(newDir, "target"), []byte(newKey), 0644)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/Valera6/doc_scraper/pkg/render"
+)
+
+// Candidates sharing less than this part of their lines with the last seen content aren't worth suggesting.
+const minSuggestionSimilarity = 0.5
+
+const maxSuggestions = 3
+
+// Class names and ids that can go into a selector as they are.
+var plainIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// suggestSelectors looks for the elements of doc whose text is the closest to previous, the content a selector matched before it went stale,
+// and returns css selectors for the best of them. Meant for after a redesign, when the content is still there under different markup.
+func suggestSelectors(doc *goquery.Document, previous string) []string {
+	previousLines := lineSet(previous)
+	if len(previousLines) == 0 {
+		return nil
+	}
+	type candidate struct {
+		node  *html.Node
+		text  string
+		score float64
+		// Whether it has an id or class to pin it down with, which makes for a sturdier selector than a bare tag.
+		named bool
+		depth int
+	}
+	var candidates []candidate
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		text := render.RenderText(s.Nodes[0])
+		if score := similarity(previousLines, lineSet(text)); score >= minSuggestionSimilarity {
+			_, hasID := s.Attr("id")
+			_, hasClass := s.Attr("class")
+			candidates = append(candidates, candidate{node: s.Nodes[0], text: text, score: score, named: hasID || hasClass, depth: s.Parents().Length()})
+		}
+	})
+	// Wrappers have the same text as what they wrap. Of those, the one to suggest is the innermost one with an id or class.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].named != candidates[j].named {
+			return candidates[i].named
+		}
+		return candidates[i].depth > candidates[j].depth
+	})
+
+	var suggestions []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c.text] {
+			continue
+		}
+		seen[c.text] = true
+		suggestions = append(suggestions, cssPath(doc, c.node))
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
+
+// lineSet is the non-blank lines of text.
+func lineSet(text string) map[string]bool {
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines[line] = true
+		}
+	}
+	return lines
+}
+
+// similarity is the part of the lines of a and b that they have in common, from 0 to 1.
+func similarity(a, b map[string]bool) float64 {
+	common := 0
+	for line := range a {
+		if b[line] {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// cssPath builds a selector for node, from its tag, id and classes, prefixed with its ancestors' until it matches only node or reaches an id.
+func cssPath(doc *goquery.Document, node *html.Node) string {
+	var parts []string
+	for n := node; n != nil && n.Type == html.ElementNode && n.Data != "html"; n = n.Parent {
+		part, isID := nodeSelector(n)
+		parts = append([]string{part}, parts...)
+		selector := strings.Join(parts, " > ")
+		if isID || doc.Find(selector).Length() == 1 {
+			return selector
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// nodeSelector is "#id" for an element with a usable id, and "tag.class1.class2" otherwise.
+func nodeSelector(n *html.Node) (string, bool) {
+	var classes []string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "id":
+			if plainIdent.MatchString(attr.Val) {
+				return "#" + attr.Val, true
+			}
+		case "class":
+			for _, class := range strings.Fields(attr.Val) {
+				if plainIdent.MatchString(class) {
+					classes = append(classes, "."+class)
+				}
+			}
+		}
+	}
+	return n.Data + strings.Join(classes, ""), false
+}
@@ -0,0 +1,48 @@
+package main
+
+// Validators a target can be checked with under --conditional, from the cheapest: a conditional request on the ETag, one on the Last-Modified,
+// or downloading and hashing the content.
+const (
+	validatorETag         = "etag"
+	validatorLastModified = "last-modified"
+	validatorHash         = "hash"
+)
+
+var validatorRanks = map[string]int{"": 0, validatorETag: 1, validatorLastModified: 2, validatorHash: 3}
+
+// chooseValidator returns the validator to check the target with from now on, after a check that downloaded the content: the cheapest one the server
+// offered that is no cheaper than the current one. Should the content have changed while the current validator stayed the same, the server can't be
+// trusted with it, and the next one down is used instead, for good. The second return value says whether that happened.
+func chooseValidator(current string, meta FetchMeta, oldETag, oldLastModified string, changed bool) (string, bool) {
+	rank := validatorRanks[current]
+	distrusted := false
+	if changed {
+		switch current {
+		case validatorETag:
+			distrusted = meta.ETag != "" && meta.ETag == oldETag
+		case validatorLastModified:
+			distrusted = meta.LastModified != "" && meta.LastModified == oldLastModified
+		}
+	}
+	if distrusted {
+		rank++
+	}
+	switch {
+	case rank <= validatorRanks[validatorETag] && meta.ETag != "":
+		return validatorETag, distrusted
+	case rank <= validatorRanks[validatorLastModified] && meta.LastModified != "":
+		return validatorLastModified, distrusted
+	default:
+		return validatorHash, distrusted
+	}
+}
+
+// conditionalFor says whether --conditional applies to the target: only to pages fetched as html, with the content coming from that page alone.
+func (args RunArgs) conditionalFor(target Target) bool {
+	entry := target.Entry
+	if !args.Conditional || entry.InnerSelector != "" || entry.NextSelector != "" || entry.FollowAsset {
+		return false
+	}
+	_, ok := args.fetcherFor(target).(HTMLFetcher)
+	return ok
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+)
+
+// A selector matching this many times more or fewer elements than on the last check likely picks something else by now.
+const matchCountDrift = 2
+
+// Problems of a selector, most pressing first.
+const (
+	// Nothing to hash but the headers, if any, so changes are missed altogether.
+	problemNoMatches = iota
+	problemMatchesDrifted
+	// Can't tell, the target has to be looked at anyway.
+	problemFetchFailed
+)
+
+var problemNames = []string{"no matches", "matches drifted", "fetch failed"}
+
+// SelectorReport is a row of `verify-selectors`.
+type SelectorReport struct {
+	Problem  string `json:"problem" yaml:"problem"`
+	ID       string `json:"id" yaml:"id"`
+	URL      string `json:"url" yaml:"url"`
+	Selector string `json:"selector" yaml:"selector"`
+	// As of the last check. nil if it wasn't counted yet.
+	Stored  *int   `json:"stored,omitempty" yaml:"stored,omitempty"`
+	Matches int    `json:"matches" yaml:"matches"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+
+	problem int
+}
+
+// matchesProblem is what's wrong with the selector going by how many elements it matches now and as of the last check, and false if nothing is.
+func matchesProblem(stored *int, matches int) (int, bool) {
+	switch {
+	case matches == 0:
+		return problemNoMatches, true
+	case stored != nil && *stored > 0 && max(matches, *stored) >= matchCountDrift*min(matches, *stored):
+		return problemMatchesDrifted, true
+	default:
+		return 0, false
+	}
+}
+
+// verifySelectors fetches every target that goes by a selector and reports the ones needing attention, without updating hashes or notifying,
+// to audit a large config before selectors silently stop monitoring anything.
+func verifySelectors(c *cli.Context) error {
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if err != nil {
+		return err
+	}
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got: %d", concurrency)
+	}
+	args := RunArgs{
+		SelectorType:      c.String("selector-type"),
+		SOCKS5:            c.String("socks5"),
+		DNSServer:         c.String("dns-server"),
+		MaxRedirects:      c.Int("max-redirects"),
+		FollowMetaRefresh: c.Bool("follow-meta-refresh"),
+		InsecureHosts:     insecureHosts(c),
+		Docs:              NewDocCache(0, c.Int("per-host-concurrency")),
+	}
+	if args.SelectorType != "css" && args.SelectorType != "xpath" {
+		return fmt.Errorf("selector type must be 'css' or 'xpath', got: %s", args.SelectorType)
+	}
+
+	var (
+		mu       sync.Mutex
+		reports  []SelectorReport
+		verified int
+	)
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for key, entry := range hashes {
+		// Only the html fetcher goes by the selector, and not when the content comes from elsewhere.
+		if !entry.IsEnabled() || entry.HeadersOnly || entry.Extractor != "" || (entry.Fetcher != "" && entry.Fetcher != "html") {
+			continue
+		}
+		target, err := newTarget(key, entry)
+		if err != nil {
+			return err
+		}
+		if _, ok := githubContentsURL(target.URL); ok && entry.Fetcher == "" {
+			continue
+		}
+		g.Go(func() error {
+			_, meta, err := HTMLFetcher{Args: args}.Fetch(context.Background(), target)
+			// Anchored targets take the whole page.
+			if err == nil && meta.Matches == nil {
+				return nil
+			}
+			report := SelectorReport{ID: targetID(key, entry), URL: target.URL, Selector: target.Selector, Stored: entry.Matches}
+			needsAttention := true
+			if err != nil {
+				report.problem, report.Error = problemFetchFailed, err.Error()
+			} else {
+				report.Matches = *meta.Matches
+				report.problem, needsAttention = matchesProblem(entry.Matches, report.Matches)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			verified++
+			if needsAttention {
+				report.Problem = problemNames[report.problem]
+				reports = append(reports, report)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].problem != reports[j].problem {
+			return reports[i].problem < reports[j].problem
+		}
+		return reports[i].ID < reports[j].ID
+	})
+
+	err = writeOutput(os.Stdout, c.String("format"), reports, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "PROBLEM\tID\tURL\tSELECTOR\tSTORED\tMATCHES\tERROR")
+		for _, r := range reports {
+			stored := "-"
+			if r.Stored != nil {
+				stored = fmt.Sprint(*r.Stored)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", r.Problem, r.ID, r.URL, r.Selector, stored, r.Matches, r.Error)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(reports) > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d of %d selectors need attention", len(reports), verified), 1)
+	}
+	return nil
+}
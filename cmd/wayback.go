@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/urfave/cli"
+)
+
+// Wayback urls end up at the timestamp of the snapshot closest to the one asked for.
+var waybackTimestamp = regexp.MustCompile(`/web/(\d{14})`)
+
+// Dates to ask the Wayback Machine for are any prefix of a timestamp, down to the year.
+var waybackDate = regexp.MustCompile(`^\d{4,14}$`)
+
+// fetchArchived gets the snapshot of pageURL closest to date from a Wayback Machine, as the page was served, without the archive's toolbar and link rewriting.
+// It returns the page and when the snapshot was actually taken.
+func fetchArchived(ctx context.Context, archive, pageURL, date string, opts FetchOptions) (*Page, time.Time, error) {
+	archivedURL := strings.TrimSuffix(archive, "/") + "/web/" + date + "id_/" + pageURL
+	client, err := newClient(opts)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	// Not through get, as the archive would take its cache-busting parameter for a part of the archived url.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archivedURL, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to fetch %s: %w", archivedURL, classifyTLS(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("failed to fetch %s: %s", archivedURL, resp.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error parsing the HTML from %s: %w", archivedURL, err)
+	}
+
+	var archivedAt time.Time
+	if match := waybackTimestamp.FindStringSubmatch(resp.Request.URL.Path); match != nil {
+		archivedAt, _ = time.Parse("20060102150405", match[1])
+	}
+	return &Page{URL: resp.Request.URL.String(), Doc: doc, Header: resp.Header}, archivedAt, nil
+}
+
+// importWayback seeds the hash of a target from an archived version of its page, so that the first live check reports everything that changed since then.
+func importWayback(c *cli.Context) error {
+	url, htmlClass, date := c.String("url"), c.String("selector"), c.String("date")
+	if url == "" || htmlClass == "" {
+		return fmt.Errorf("--url and --selector are required")
+	}
+	if !waybackDate.MatchString(date) {
+		return fmt.Errorf("--date must be a timestamp like 20230115 or 20230115093000, got: %s", date)
+	}
+	filePath, err := hashesPath(c)
+	if err != nil {
+		return err
+	}
+	hashes, err := loadHashes(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		hashes, err = make(Hashes), nil
+	}
+	if err != nil {
+		return err
+	}
+	args := RunArgs{
+		SelectorType:      c.String("selector-type"),
+		RawText:           c.Bool("raw-text"),
+		SOCKS5:            c.String("socks5"),
+		DNSServer:         c.String("dns-server"),
+		MaxRedirects:      c.Int("max-redirects"),
+		FollowMetaRefresh: c.Bool("follow-meta-refresh"),
+		InsecureHosts:     insecureHosts(c),
+	}
+	if args.SelectorType != "css" && args.SelectorType != "xpath" {
+		return fmt.Errorf("selector type must be 'css' or 'xpath', got: %s", args.SelectorType)
+	}
+
+	// An existing target keeps its options, only its hashes are replaced.
+	key := joinKey(url, htmlClass)
+	entry := &Entry{}
+	if existing, ok := hashes[key]; ok {
+		copied := *existing
+		entry = &copied
+	}
+	page, archivedAt, err := fetchArchived(context.Background(), c.String("archive"), url, date, args.fetchOptions(url, entry))
+	if err != nil {
+		return err
+	}
+	extraction := args.extraction(entry)
+	contentBlock, err := extractContent(page.Doc, htmlClass, extraction)
+	if err != nil {
+		return fmt.Errorf("failed to extract content from %s: %w", page.URL, err)
+	}
+	if strings.TrimSpace(contentBlock) == "" {
+		return fmt.Errorf("%s matched nothing in the snapshot at %s", htmlClass, page.URL)
+	}
+	entry.Hash = getSHA256Hash(contentBlock)
+	entry.Selectors = nil
+	if extraction.SelectorType != "xpath" {
+		entry.Selectors = hashSelectors(page.Doc, htmlClass, args.RawText)
+	}
+	if !archivedAt.IsZero() {
+		entry.LastChanged = &archivedAt
+	}
+	hashes[key] = entry
+
+	if snapshotDir := c.String("snapshot-dir"); snapshotDir != "" {
+		snapshotDir, err = expandHome(snapshotDir)
+		if err != nil {
+			return err
+		}
+		at := archivedAt
+		if at.IsZero() {
+			at = time.Now()
+		}
+		if err := (SnapshotStore{Dir: snapshotDir, IDs: hashes.IDs()}).Save(key, contentBlock, at); err != nil {
+			return err
+		}
+	}
+	if err := saveHashes(filePath, hashes, c.Bool("compact")); err != nil {
+		return err
+	}
+	fmt.Printf("Seeded %s (%s) from the snapshot at %s, %d characters\n", url, htmlClass, page.URL, len(contentBlock))
+	return nil
+}
@@ -0,0 +1,170 @@
+// Package checker checks pages for changes from within another Go program, with the pages and their hashes coming from wherever it keeps them.
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Kinds of ChangeEvent.
+const (
+	Changed   = "changed"
+	Unchanged = "unchanged"
+	Error     = "error"
+)
+
+// ChangeEvent is the outcome of checking a single target, the same as a line of doc_scraper's --events-file.
+type ChangeEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Selector  string    `json:"selector"`
+	Event     string    `json:"event"` // Changed | Unchanged | Error
+	OldHash   string    `json:"oldHash"`
+	NewHash   string    `json:"newHash"`
+	Error     string    `json:"error,omitempty"`
+	// Changed, but not notified of, ex. by less than --min-change-lines.
+	Minor bool `json:"minor,omitempty"`
+}
+
+// Target is a single tracked url and selector.
+type Target struct {
+	// What the Store knows the target by.
+	Key      string
+	ID       string
+	URL      string
+	Selector string
+}
+
+// Fetcher gets the content of a target that is hashed.
+type Fetcher interface {
+	Fetch(ctx context.Context, target Target) (string, error)
+}
+
+// Store keeps the hash of every target's content as of its last check. The Checker calls it from a single goroutine at a time.
+type Store interface {
+	// Targets to check, in the order they're checked in.
+	Targets() []Target
+	// Hash of the target's content, empty if it was never checked.
+	Hash(key string) string
+	SetHash(key, hash string) error
+}
+
+// Checker checks the targets of a Store in passes, fetching their content with a Fetcher.
+type Checker struct {
+	Fetcher Fetcher
+	Store   Store
+	// Number of targets checked at a time, at least 1.
+	Concurrency int
+	// Number of events Stream buffers for a consumer that falls behind. 0 for none, in which case every check waits for its event to be received.
+	Buffer int
+	// Time between the start of one pass and the next, for Stream to keep checking until its ctx is done. 0 for a single pass.
+	Interval time.Duration
+
+	mu sync.Mutex
+}
+
+// Check checks every target once, and returns their events in the order the checks ended.
+func (c *Checker) Check(ctx context.Context) []ChangeEvent {
+	var (
+		mu     sync.Mutex
+		events []ChangeEvent
+	)
+	c.pass(ctx, func(event ChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return events
+}
+
+// Stream checks every target, emitting an event per target as soon as its check ends rather than once the pass is over.
+// Once the buffer is full, checks wait for the consumer before going on, so a slow consumer slows the checks down but no event is dropped.
+// The channel is closed after the last pass, or once ctx is done, with the events of the checks that were in flight dropped.
+func (c *Checker) Stream(ctx context.Context) <-chan ChangeEvent {
+	events := make(chan ChangeEvent, c.Buffer)
+	go func() {
+		defer close(events)
+		for {
+			start := time.Now()
+			c.pass(ctx, func(event ChangeEvent) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+				}
+			})
+			if c.Interval <= 0 {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(start.Add(c.Interval))):
+			}
+		}
+	}()
+	return events
+}
+
+// pass checks the targets, at most Concurrency at a time, passing the event of each to emit. Once ctx is done no new checks are started.
+func (c *Checker) pass(ctx context.Context, emit func(ChangeEvent)) {
+	c.mu.Lock()
+	targets := c.Store.Targets()
+	c.mu.Unlock()
+	var g errgroup.Group
+	g.SetLimit(max(c.Concurrency, 1))
+	for _, target := range targets {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			event := c.check(ctx, target)
+			if ctx.Err() != nil {
+				return nil
+			}
+			emit(event)
+			return nil
+		})
+	}
+	g.Wait()
+}
+
+// check fetches the target's content, and updates its hash in the Store when it changed.
+func (c *Checker) check(ctx context.Context, target Target) ChangeEvent {
+	event := ChangeEvent{
+		Timestamp: time.Now(),
+		ID:        target.ID,
+		URL:       target.URL,
+		Selector:  target.Selector,
+	}
+	content, err := c.Fetcher.Fetch(ctx, target)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	event.OldHash = c.Store.Hash(target.Key)
+	if err != nil {
+		event.Event, event.Error = Error, err.Error()
+		return event
+	}
+	event.NewHash = Hash(content)
+	if event.NewHash == event.OldHash {
+		event.Event = Unchanged
+		return event
+	}
+	if err := c.Store.SetHash(target.Key, event.NewHash); err != nil {
+		event.Event, event.NewHash, event.Error = Error, "", err.Error()
+		return event
+	}
+	event.Event = Changed
+	return event
+}
+
+// Hash is what the content of targets is compared by, its sha256 in hex.
+func Hash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pages is a Fetcher of the content by url, failing for the urls that have none.
+type pages map[string]string
+
+func (p pages) Fetch(ctx context.Context, target Target) (string, error) {
+	content, ok := p[target.URL]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return content, nil
+}
+
+// hashes is a Store of the hashes by url, with the url as the key.
+type hashes map[string]string
+
+func (h hashes) Targets() []Target {
+	targets := make([]Target, 0, len(h))
+	for url := range h {
+		targets = append(targets, Target{Key: url, URL: url})
+	}
+	return targets
+}
+
+func (h hashes) Hash(key string) string { return h[key] }
+
+func (h hashes) SetHash(key, hash string) error {
+	h[key] = hash
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	store := hashes{"/a": Hash("a"), "/b": "outdated", "/c": "", "/d": Hash("d")}
+	checker := Checker{
+		Fetcher:     pages{"/a": "a", "/b": "b", "/c": "c"},
+		Store:       store,
+		Concurrency: 2,
+	}
+	var got []string
+	for _, event := range checker.Check(context.Background()) {
+		got = append(got, event.URL+" "+event.Event)
+	}
+	sort.Strings(got)
+	want := []string{"/a unchanged", "/b changed", "/c changed", "/d error"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if store["/b"] != Hash("b") || store["/c"] != Hash("c") {
+		t.Errorf("hashes = %v, want /b and /c updated", store)
+	}
+	if store["/d"] != Hash("d") {
+		t.Errorf("hash of /d = %s, want it kept after failing to fetch it", store["/d"])
+	}
+}
+
+// counting is a Fetcher of the url as the content, counting how many fetches started.
+type counting struct {
+	mu      sync.Mutex
+	started int
+}
+
+func (f *counting) Fetch(ctx context.Context, target Target) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started++
+	return target.URL, nil
+}
+
+func TestStreamBackpressure(t *testing.T) {
+	fetcher := &counting{}
+	checker := Checker{
+		Fetcher:     fetcher,
+		Store:       hashes{"/a": "", "/b": "", "/c": "", "/d": ""},
+		Concurrency: 1,
+		Buffer:      1,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events := checker.Stream(ctx)
+	// Nothing is received yet: one event in the buffer, a second check waiting to send its own, and no third one started.
+	time.Sleep(100 * time.Millisecond)
+	fetcher.mu.Lock()
+	started := fetcher.started
+	fetcher.mu.Unlock()
+	if started != 2 {
+		t.Errorf("%d checks started before any event was received, want 2", started)
+	}
+	var received int
+	for range events {
+		received++
+	}
+	if received != 4 {
+		t.Errorf("received %d events, want 4", received)
+	}
+}
+
+func TestStreamStopsWithContext(t *testing.T) {
+	checker := Checker{
+		Fetcher:     pages{"/a": "same"},
+		Store:       hashes{"/a": ""},
+		Concurrency: 1,
+		Buffer:      1,
+		Interval:    10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := checker.Stream(ctx)
+	// Keeps checking pass after pass until cancelled.
+	want := []string{Changed, Unchanged, Unchanged}
+	for i := range want {
+		select {
+		case event := <-events:
+			if event.Event != want[i] {
+				t.Errorf("pass %d: event = %s, want %s", i+1, event.Event, want[i])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event for pass %d", i+1)
+		}
+	}
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("the channel wasn't closed after the context was cancelled")
+		}
+	}
+}
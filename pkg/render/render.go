@@ -0,0 +1,112 @@
+// Package render renders html as text the way a browser lays it out, for content to be compared or hashed regardless of its markup.
+package render
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements that are laid out on lines of their own.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "caption": true, "dd": true, "details": true,
+	"dialog": true, "div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true,
+	"hr": true, "html": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "section": true, "summary": true,
+	"table": true, "tbody": true, "tfoot": true, "thead": true, "tr": true, "ul": true,
+}
+
+// Elements whose content is never rendered.
+var hiddenElements = map[string]bool{
+	"head": true, "noscript": true, "script": true, "style": true, "template": true,
+}
+
+// RenderText returns the text of the nodes the way a browser renders it: block elements start new lines, whitespace inside a line is collapsed
+// to single spaces, <pre> is kept as is, and scripts and styles are left out. Empty lines are dropped.
+// Selecting the same content through different elements gives the same text, which makes it what the hashes are computed from.
+func RenderText(nodes ...*html.Node) string {
+	r := &textRenderer{}
+	for _, node := range nodes {
+		r.render(node)
+		r.breakLine()
+	}
+	return strings.Join(r.lines, "\n")
+}
+
+type textRenderer struct {
+	lines []string
+	line  strings.Builder
+}
+
+func (r *textRenderer) render(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		r.write(node.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			r.render(child)
+		}
+		return
+	default:
+		return
+	}
+
+	switch {
+	case hiddenElements[node.Data]:
+		return
+	case node.Data == "br":
+		r.breakLine()
+		return
+	case node.Data == "pre":
+		r.breakLine()
+		r.lines = append(r.lines, strings.Split(strings.TrimRight(RawText(node), "\n"), "\n")...)
+		return
+	case node.Data == "td" || node.Data == "th":
+		r.write(" ")
+	}
+
+	block := blockElements[node.Data]
+	if block {
+		r.breakLine()
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.render(child)
+	}
+	if block {
+		r.breakLine()
+	}
+}
+
+// write appends inline text to the current line, collapsing whitespace as it goes.
+func (r *textRenderer) write(text string) {
+	for _, c := range text {
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' {
+			if current := r.line.String(); current != "" && !strings.HasSuffix(current, " ") {
+				r.line.WriteByte(' ')
+			}
+			continue
+		}
+		r.line.WriteRune(c)
+	}
+}
+
+func (r *textRenderer) breakLine() {
+	if line := strings.TrimRight(r.line.String(), " "); line != "" {
+		r.lines = append(r.lines, line)
+	}
+	r.line.Reset()
+}
+
+// RawText is all the text under the node exactly as it is in the source, same as goquery's Text().
+func RawText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(RawText(child))
+	}
+	return b.String()
+}
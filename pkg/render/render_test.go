@@ -0,0 +1,64 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func parseBody(t *testing.T, source string) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader("<html><body>" + source + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// html > head, body
+	return doc.FirstChild.LastChild
+}
+
+func TestRenderText(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"block elements", "<h1>Limits</h1><p>Weight 1200</p><div>Orders 50</div>", "Limits\nWeight 1200\nOrders 50"},
+		{"inline elements", "<p>Weight <b>1200</b> per <a href='#'>minute</a></p>", "Weight 1200 per minute"},
+		{"inline between blocks", "<div>one<span> two </span>three</div>", "one two three"},
+		{"whitespace collapsing", "<p>\n    Weight\t\t1200   per\r\n  minute  \n</p>", "Weight 1200 per minute"},
+		{"br", "<p>first<br>second<br/>third</p>", "first\nsecond\nthird"},
+		{"consecutive brs leave no empty lines", "<p>first<br><br><br>second</p>", "first\nsecond"},
+		{"unordered list", "<ul><li>GET /api/v3/order</li><li> POST /api/v3/order </li></ul>", "GET /api/v3/order\nPOST /api/v3/order"},
+		{"nested list", "<ol><li>Spot<ul><li>0.1%</li></ul></li><li>Futures</li></ol>", "Spot\n0.1%\nFutures"},
+		{"table cells", "<table><tr><th>Tier</th><th>Fee</th></tr><tr><td>VIP 0</td><td>0.1%</td></tr></table>", "Tier Fee\nVIP 0 0.1%"},
+		{"pre kept as is", "<pre>{\n  \"a\":   1\n}</pre>", "{\n  \"a\":   1\n}"},
+		{"hidden elements", "<p>shown</p><script>var hidden = 1;</script><style>p {}</style><template><p>hidden</p></template>", "shown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderText(parseBody(t, tt.source)); got != tt.want {
+				t.Errorf("RenderText(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+// The same content selected through different elements renders the same.
+func TestRenderTextSelectionBoundaries(t *testing.T) {
+	body := parseBody(t, "<div class='content'>\n  <p>Weight 1200</p>\n  <p>Orders 50</p>\n</div>")
+	content := body.FirstChild
+	var paragraphs []*html.Node
+	for child := content.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			paragraphs = append(paragraphs, child)
+		}
+	}
+	whole, parts := RenderText(content), RenderText(paragraphs...)
+	if whole != parts {
+		t.Errorf("the div renders as %q, its paragraphs as %q", whole, parts)
+	}
+	if whole != "Weight 1200\nOrders 50" {
+		t.Errorf("RenderText = %q", whole)
+	}
+}
@@ -1,19 +1,28 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
 )
 
-func Msg(botToken string, chatID int64, msg string) {
-	bot, err := tgbotapi.NewBotAPI(botToken)
+// Bot API url format, a variable for tests to point at a fake server.
+var apiEndpoint = tgbotapi.APIEndpoint
+
+// Msg sends msg to every one of chatIDs. A chat that fails doesn't stop the rest, all the errors are returned together.
+func Msg(botToken string, chatIDs []int64, msg string) error {
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(botToken, apiEndpoint)
 	if err != nil {
-		log.Panic("Failed to create bot:", err)
+		return fmt.Errorf("failed to create bot: %w", err)
 	}
 
-	message := tgbotapi.NewMessage(chatID, msg)
-	_, err = bot.Send(message)
-	if err != nil {
-		log.Println("Error sending message:", err)
+	var errs []error
+	for _, chatID := range chatIDs {
+		message := tgbotapi.NewMessage(chatID, msg)
+		if _, err := bot.Send(message); err != nil {
+			errs = append(errs, fmt.Errorf("error sending message to chat %d: %w", chatID, err))
+		}
 	}
+	return errors.Join(errs...)
 }
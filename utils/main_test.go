@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTelegram answers getMe and sendMessage like the Bot API does, failing for chat 13, and records the chats messages were sent to.
+func fakeTelegram(t *testing.T) *[]string {
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"doc_scraper","username":"doc_scraper_bot"}}`)
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+			chat := r.FormValue("chat_id")
+			if chat == "13" {
+				fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+				return
+			}
+			mu.Lock()
+			sent = append(sent, chat+": "+r.FormValue("text"))
+			mu.Unlock()
+			fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":%s,"type":"group"}}}`, chat)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	previous := apiEndpoint
+	apiEndpoint = server.URL + "/bot%s/%s"
+	t.Cleanup(func() { apiEndpoint = previous })
+	return &sent
+}
+
+func TestMsgSingleChat(t *testing.T) {
+	sent := fakeTelegram(t)
+	if err := Msg("123:ABC", []int64{-1001}, "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-1001: changed"}; !slices.Equal(*sent, want) {
+		t.Errorf("sent %q, want %q", *sent, want)
+	}
+}
+
+func TestMsgMultipleChats(t *testing.T) {
+	sent := fakeTelegram(t)
+	if err := Msg("123:ABC", []int64{-1001, 42}, "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-1001: changed", "42: changed"}; !slices.Equal(*sent, want) {
+		t.Errorf("sent %q, want %q", *sent, want)
+	}
+}
+
+func TestMsgFailingChatDoesntStopTheOthers(t *testing.T) {
+	sent := fakeTelegram(t)
+	err := Msg("123:ABC", []int64{13, 42}, "changed")
+	if err == nil || !strings.Contains(err.Error(), "chat 13") {
+		t.Errorf("error = %v, want one for chat 13", err)
+	}
+	if want := []string{"42: changed"}; !slices.Equal(*sent, want) {
+		t.Errorf("sent %q, want %q", *sent, want)
+	}
+}